@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/controller"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Exit codes of the one-shot commands
+const (
+	exitOK              = 0
+	exitError           = 1
+	exitUsage           = 2
+	exitUpdateAvailable = 10
+)
+
+// oneShotCommands describes the subcommands that run once and exit
+var oneShotCommands = []struct {
+	name, summary string
+}{
+	{"check", "Compare the installed build with the latest build; exits 10 when an update is available"},
+	{"update", "Install the latest build and restart targets if an update is available (-force to always run)"},
+	{"validate", "Validate the installed files without restarting anything"},
+	{"restart", "Restart every target workload without updating (-outdated for only those on an older build)"},
+	{"status", "Show the persisted update state and target workloads"},
+}
+
+func isOneShot(command string) bool {
+	for _, c := range oneShotCommands {
+		if c.name == command {
+			return true
+		}
+	}
+	return false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [-output human|json] [command flags]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  run       Run the update loop and HTTP server (default)")
+	for _, c := range oneShotCommands {
+		fmt.Fprintf(out, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(out, "\nExit codes: 0 success or up to date, 1 failure, 2 usage or configuration error, 10 update available (check)")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// oneShotOptions are the parsed flags of a one-shot command
+type oneShotOptions struct {
+	command      string
+	out          *printer
+	force        bool
+	outdatedOnly bool
+	dryRun       bool
+}
+
+// parseOneShot parses a one-shot command's flags
+func parseOneShot(command string, args []string) (*oneShotOptions, error) {
+	opts := &oneShotOptions{command: command}
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	format := fs.String("output", "human", "Output format: human or json")
+	switch command {
+	case "update":
+		fs.BoolVar(&opts.force, "force", false, "Run app_update and restart targets even if no update is available")
+	case "restart":
+		fs.BoolVar(&opts.outdatedOnly, "outdated", false, "Only restart workloads with pods running an older build")
+	}
+	switch command {
+	case "update", "validate", "restart":
+		fs.BoolVar(&opts.dryRun, "dry-run", false, "Print a plan instead of running steamcmd or changing Kubernetes objects (also DRY_RUN)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *format != "human" && *format != "json" {
+		return nil, fmt.Errorf("invalid -output %q, must be human or json", *format)
+	}
+
+	opts.out = &printer{json: *format == "json", w: os.Stdout}
+	return opts, nil
+}
+
+// runOneShot runs a single command against the app configured in the environment and returns the exit code
+func runOneShot(opts *oneShotOptions, config *controller.Config, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) int {
+	out := opts.out
+
+	if config.PoliciesEnabled {
+		return out.fail(fmt.Errorf("%s acts on the app configured in the environment and is not available with POLICIES_ENABLED", opts.command), exitUsage)
+	}
+
+	if opts.dryRun {
+		config.DryRun = true
+	}
+	dryRun := config.DryRun && opts.command != "check" && opts.command != "status"
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	uc := controller.NewUpdateController(config, clientset, dynamicClient, newSteamClient(config))
+	if dryRun {
+		return runDryRun(ctx, uc, opts)
+	}
+
+	switch opts.command {
+	case "check":
+		result, err := uc.Check(ctx)
+		if err != nil {
+			return out.fail(err, exitError)
+		}
+		out.print(result, func(w io.Writer) { printCheck(w, result) })
+		if result.UpdateAvailable {
+			return exitUpdateAvailable
+		}
+
+	case "update":
+		result, err := uc.Update(ctx, opts.force)
+		if result == nil && err != nil {
+			return out.fail(err, exitError)
+		}
+		out.print(updateOutput{Updated: result != nil, Result: result}, func(w io.Writer) { printUpdate(w, result) })
+		if err != nil {
+			return exitError
+		}
+
+	case "validate":
+		if err := uc.Validate(ctx); err != nil {
+			return out.fail(err, exitError)
+		}
+		out.print(map[string]bool{"valid": true}, func(w io.Writer) { fmt.Fprintln(w, "Validation succeeded") })
+
+	case "restart":
+		result, err := uc.RestartTargets(ctx, opts.outdatedOnly)
+		if result == nil {
+			if errors.Is(err, controller.ErrNoTargets) || err == nil {
+				out.print(&controller.RestartResult{}, func(w io.Writer) { fmt.Fprintln(w, "No workloads to restart") })
+				return exitOK
+			}
+			return out.fail(err, exitError)
+		}
+		out.print(result, func(w io.Writer) { printRestart(w, result) })
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+
+	case "status":
+		status := uc.Snapshot(ctx)
+		out.print(status, func(w io.Writer) { printStatus(w, status) })
+	}
+
+	return exitOK
+}
+
+// runDryRun runs a command in dry-run mode and prints the plan it produced
+func runDryRun(ctx context.Context, uc *controller.UpdateController, opts *oneShotOptions) int {
+	var err error
+	switch opts.command {
+	case "update":
+		_, err = uc.Update(ctx, opts.force)
+	case "validate":
+		err = uc.Validate(ctx)
+	case "restart":
+		_, err = uc.RestartTargets(ctx, opts.outdatedOnly)
+		if errors.Is(err, controller.ErrNoTargets) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return opts.out.fail(err, exitError)
+	}
+
+	plan := uc.Plan()
+	opts.out.print(dryRunOutput{DryRun: true, Plan: plan}, func(w io.Writer) { printPlan(w, plan) })
+	return exitOK
+}
+
+// dryRunOutput is the JSON output of a command run with -dry-run
+type dryRunOutput struct {
+	DryRun bool                   `json:"dryRun"`
+	Plan   *controller.DryRunPlan `json:"plan"`
+}
+
+// updateOutput is the JSON output of the update command
+type updateOutput struct {
+	Updated bool                     `json:"updated"`
+	Result  *controller.UpdateResult `json:"result,omitempty"`
+}
+
+// printer writes command output as JSON or as human-readable text
+type printer struct {
+	json bool
+	w    io.Writer
+}
+
+func (p *printer) print(value any, human func(io.Writer)) {
+	if !p.json {
+		human(p.w)
+		return
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode output: %v\n", err)
+	}
+}
+
+// fail reports an error, as {"error": ...} on stdout in JSON mode, and returns code
+func (p *printer) fail(err error, code int) int {
+	if p.json {
+		p.print(map[string]string{"error": err.Error()}, nil)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return code
+}
+
+func printCheck(w io.Writer, result *controller.CheckResult) {
+	fmt.Fprintf(w, "%s (%s, branch %s)\n", result.App, result.AppID, result.Branch)
+	fmt.Fprintf(w, "  Installed build: %s\n", valueOr(result.InstalledBuild, "not installed"))
+	fmt.Fprintf(w, "  Latest build:    %s\n", valueOr(result.LatestBuild, "unknown"))
+	if result.UpdateAvailable {
+		fmt.Fprintln(w, "Update available")
+	} else {
+		fmt.Fprintln(w, "Up to date")
+	}
+}
+
+func printUpdate(w io.Writer, result *controller.UpdateResult) {
+	if result == nil {
+		fmt.Fprintln(w, "Already up to date, nothing to do")
+		return
+	}
+
+	duration := result.FinishedAt.Sub(result.StartedAt).Round(time.Second)
+	if result.Success {
+		fmt.Fprintf(w, "Update succeeded in %s\n", duration)
+	} else {
+		fmt.Fprintf(w, "Update failed after %s: %s\n", duration, result.Error)
+	}
+	if result.Restart != nil {
+		printRestart(w, result.Restart)
+	}
+	for _, finding := range result.LogFindings {
+		fmt.Fprintf(w, "  Log finding: %s\n", finding)
+	}
+}
+
+func printRestart(w io.Writer, result *controller.RestartResult) {
+	fmt.Fprintf(w, "%d workloads restarted, %d failed, %d skipped\n", len(result.Succeeded), len(result.Failed), len(result.Skipped))
+	for _, o := range result.Succeeded {
+		fmt.Fprintf(w, "  restarted %s\n", o)
+	}
+	for _, o := range result.Failed {
+		fmt.Fprintf(w, "  failed    %s\n", o)
+	}
+	for _, o := range result.Skipped {
+		fmt.Fprintf(w, "  skipped   %s\n", o)
+	}
+}
+
+func printPlan(w io.Writer, plan *controller.DryRunPlan) {
+	if plan == nil {
+		fmt.Fprintln(w, "Dry run: nothing to do")
+		return
+	}
+
+	fmt.Fprintf(w, "Dry run plan for %s\n", plan.Trigger)
+	fmt.Fprintf(w, "  Installed build: %s\n", valueOr(plan.InstalledBuild, "not installed"))
+	if plan.Trigger == "update" {
+		fmt.Fprintf(w, "  Target build:    %s\n", valueOr(plan.TargetBuild, "unknown"))
+	}
+	if len(plan.Steps) > 0 {
+		fmt.Fprintln(w, "\nsteamcmd:")
+		for i, step := range plan.Steps {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+		}
+	}
+	if len(plan.Recovery) > 0 {
+		fmt.Fprintln(w, "\nRecovery:")
+		for _, recovery := range plan.Recovery {
+			fmt.Fprintf(w, "  - %s\n", recovery)
+		}
+	}
+	if len(plan.Restart) > 0 {
+		fmt.Fprintln(w, "\nRestart order:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  #\tWORKLOAD\tPODS\tPOLICY\tSTRATEGY\tPRIORITY")
+		for i, restart := range plan.Restart {
+			fmt.Fprintf(tw, "  %d\t%s %s/%s\t%d\t%s\t%s\t%d\n", i+1, restart.Kind, restart.Namespace, restart.Name, restart.Pods, restart.Policy, restart.Strategy, restart.Priority)
+		}
+		tw.Flush()
+	}
+	for _, excluded := range plan.Excluded {
+		fmt.Fprintf(w, "  excluded %s/%s: %s\n", excluded.Namespace, excluded.Name, excluded.Reason)
+	}
+}
+
+func printStatus(w io.Writer, status controller.Status) {
+	fmt.Fprintf(w, "%s (%s, branch %s)\n", status.App, status.AppID, status.Branch)
+	fmt.Fprintf(w, "  Installed build: %s\n", valueOr(status.InstalledBuild, "not installed"))
+	fmt.Fprintf(w, "  Phase:           %s\n", status.Phase)
+	fmt.Fprintf(w, "  Paused:          %t\n", status.Paused)
+	if status.RetryCount > 0 {
+		fmt.Fprintf(w, "  Retries:         %d\n", status.RetryCount)
+	}
+
+	if len(status.Targets) == 0 {
+		fmt.Fprintln(w, "No target pods")
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WORKLOAD\tPOD\tBUILD\tAGE")
+	for _, target := range status.Targets {
+		for _, pod := range target.Pods {
+			build := pod.Build
+			if pod.Outdated {
+				build = "older"
+			}
+			age := "unknown"
+			if !pod.StartedAt.IsZero() {
+				age = time.Since(pod.StartedAt).Round(time.Second).String()
+			}
+			fmt.Fprintf(tw, "%s %s/%s\t%s\t%s\t%s\n", target.Kind, target.Namespace, target.Name, pod.Name, build, age)
+		}
+	}
+	tw.Flush()
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
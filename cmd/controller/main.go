@@ -2,36 +2,74 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/UDL-TF/RestartController/pkg/k8s"
+	// Maintenance windows name IANA time zones, which the base image may not ship
+	_ "time/tzdata"
+
 	"github.com/UDL-TF/UpdateController/internal/controller"
+	"github.com/UDL-TF/UpdateController/internal/server"
 	"github.com/UDL-TF/UpdateController/internal/steamcmd"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 )
 
+// runner is the controller main loop, either for one app or for every GameUpdatePolicy
+type runner interface {
+	Run(ctx context.Context) error
+	Healthy() error
+	Ready(ctx context.Context) error
+	Reload(config *controller.Config)
+	server.Controller
+}
+
 func main() {
 	klog.InitFlags(nil)
 
-	var kubeconfig string
+	var kubeconfig, configFile string
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file (optional, uses in-cluster config if not provided)")
+	flag.StringVar(&configFile, "config", os.Getenv("CONFIG_FILE"), "Path to a YAML or JSON config file; environment variables override its values")
+	flag.Usage = usage
 	flag.Parse()
 
-	// Load configuration from environment
-	config := controller.LoadConfig()
+	command := "run"
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+	var oneShot *oneShotOptions
+	if command != "run" {
+		if !isOneShot(command) {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+			usage()
+			os.Exit(exitUsage)
+		}
 
-	klog.Infof("Starting UpdateController for %s (AppID: %s)", config.SteamApp, config.SteamAppID)
-	klog.Infof("Check interval: %s", config.CheckInterval)
-	klog.Infof("Namespace: %s", config.Namespace)
-	klog.Infof("Pod selector: %s", config.PodSelector)
+		var err error
+		if oneShot, err = parseOneShot(command, flag.Args()[1:]); err != nil {
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(exitUsage)
+		}
+	}
+
+	// Load configuration from the config file and environment, refusing to start on any problem
+	config, err := controller.LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
 
 	// Initialize Kubernetes client
 	k8sConfig, err := buildKubeConfig(kubeconfig)
@@ -44,19 +82,46 @@ func main() {
 		klog.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
 
-	k8sClient := k8s.NewClient(clientset, config.Namespace)
+	dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		klog.Fatalf("Failed to create dynamic Kubernetes client: %v", err)
+	}
 
-	// Initialize SteamCMD client
-	steamClient := steamcmd.NewClient(
-		config.SteamCMDPath,
-		config.SteamApp,
-		config.SteamAppID,
-		config.GameMountPath,
-		config.UpdateScript,
-	)
+	if oneShot == nil {
+		run(config, configFile, clientset, dynamicClient)
+		return
+	}
 
-	// Create controller
-	ctrl := controller.NewUpdateController(config, k8sClient, steamClient)
+	code := runOneShot(oneShot, config, clientset, dynamicClient)
+	klog.Flush()
+	os.Exit(code)
+}
+
+// run starts the update loop and serves HTTP until SIGINT or SIGTERM,
+// reloading configuration whenever configFile changes
+func run(config *controller.Config, configFile string, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) {
+	if config.PoliciesEnabled {
+		klog.Info("Starting UpdateController in GameUpdatePolicy mode")
+	} else {
+		klog.Infof("Starting UpdateController for %s (AppID: %s, branch: %s)", config.SteamApp, config.SteamAppID, config.SteamBranch)
+		klog.Infof("Check interval: %s", config.CheckInterval)
+		klog.Infof("Namespaces: %v", config.Namespaces)
+		if config.NamespaceSelector != "" {
+			klog.Infof("Namespace selector: %s", config.NamespaceSelector)
+		}
+		klog.Infof("Pod selector: %s", config.PodSelector)
+		if config.FieldSelector != "" {
+			klog.Infof("Field selector: %s", config.FieldSelector)
+		}
+	}
+
+	// Create controller, either for every GameUpdatePolicy or for the app configured in the environment
+	var ctrl runner
+	if config.PoliciesEnabled {
+		ctrl = controller.NewPolicyReconciler(config, clientset, dynamicClient)
+	} else {
+		ctrl = controller.NewUpdateController(config, clientset, dynamicClient, newSteamClient(config))
+	}
 
 	// Setup signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -65,6 +130,24 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Serve metrics and probes
+	httpServer := server.New(config.HTTPAddr)
+	httpServer.Handle("/metrics", promhttp.Handler())
+	httpServer.Handle("/healthz", server.Probe(func(context.Context) error { return ctrl.Healthy() }))
+	httpServer.Handle("/readyz", server.Probe(ctrl.Ready))
+	if config.AdminToken != "" {
+		httpServer.Handle("/api/", server.AdminAPI(ctrl, config.AdminToken))
+		httpServer.Handle("/ui/", server.Dashboard("/ui/"))
+		httpServer.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+	} else {
+		klog.Info("ADMIN_TOKEN not set, admin API and dashboard disabled")
+	}
+	go func() {
+		if err := httpServer.Run(ctx); err != nil {
+			klog.Errorf("HTTP server error: %v", err)
+		}
+	}()
+
 	// Start controller
 	go func() {
 		if err := ctrl.Run(ctx); err != nil {
@@ -73,6 +156,10 @@ func main() {
 		}
 	}()
 
+	if configFile != "" {
+		go controller.WatchConfig(ctx, configFile, config, ctrl.Reload)
+	}
+
 	// Wait for shutdown signal
 	sig := <-sigChan
 	klog.Infof("Received signal %v, shutting down gracefully...", sig)
@@ -83,6 +170,19 @@ func main() {
 	klog.Info("Shutdown complete")
 }
 
+// newSteamClient creates the SteamCMD client for the app configured in the environment
+func newSteamClient(config *controller.Config) *steamcmd.Client {
+	steamClient := steamcmd.NewClient(
+		config.SteamCMDPath,
+		config.SteamApp,
+		config.SteamAppID,
+		config.GameMountPath,
+		config.UpdateScript,
+	)
+	steamClient.SetBranch(config.SteamBranch)
+	return steamClient
+}
+
 // buildKubeConfig builds Kubernetes configuration from kubeconfig file or in-cluster config
 func buildKubeConfig(kubeconfig string) (*rest.Config, error) {
 	if kubeconfig != "" {
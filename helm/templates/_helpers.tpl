@@ -76,3 +76,47 @@ Get the PVC name
 {{- printf "%s-game-files" (include "update-controller.fullname" .) }}
 {{- end }}
 {{- end }}
+
+{{/*
+Controller settings keyed by environment variable name, used as ConfigMap
+keys or, with configReload enabled, as the mounted config file
+*/}}
+{{- define "update-controller.settings" -}}
+CHECK_INTERVAL: {{ .Values.config.checkInterval | quote }}
+STEAMCMD_PATH: {{ .Values.config.steamcmdPath | quote }}
+STEAMAPP: {{ .Values.config.steamApp | quote }}
+STEAMAPPID: {{ .Values.config.steamAppId | quote }}
+STEAM_BRANCH: {{ .Values.config.steamBranch | quote }}
+GAME_MOUNT_PATH: {{ .Values.config.gameMountPath | quote }}
+UPDATE_SCRIPT: {{ .Values.config.updateScript | quote }}
+POD_SELECTOR: {{ .Values.config.podSelector | quote }}
+FIELD_SELECTOR: {{ .Values.config.fieldSelector | quote }}
+MAX_RETRIES: {{ .Values.config.maxRetries | quote }}
+RETRY_DELAY: {{ .Values.config.retryDelay | quote }}
+STATE_FILE: {{ .Values.config.stateFile | quote }}
+STEAMCMD_TIMEOUT: {{ .Values.config.steamcmdTimeout | quote }}
+HTTP_ADDR: {{ printf ":%v" .Values.service.port | quote }}
+NAMESPACE: {{ .Values.config.namespace | quote }}
+NAMESPACES: {{ .Values.config.namespaces | quote }}
+NAMESPACE_SELECTOR: {{ .Values.config.namespaceSelector | quote }}
+NAMESPACE_POD_SELECTORS: {{ .Values.config.namespacePodSelectors | quote }}
+LOG_SCAN_WINDOW: {{ .Values.config.logScanWindow | quote }}
+LOG_SCAN_RULES: {{ join "\n" .Values.config.logScanRules | quote }}
+LOG_TRIGGER_ENABLED: {{ .Values.config.logTriggerEnabled | quote }}
+LOG_TRIGGER_COOLDOWN: {{ .Values.config.logTriggerCooldown | quote }}
+AGONES_ALLOCATED_WAIT: {{ .Values.config.agonesAllocatedWait | quote }}
+UNKNOWN_KIND_ACTIONS: {{ .Values.config.unknownKindActions | quote }}
+RESTART_STRATEGY: {{ .Values.config.restartStrategy | quote }}
+POD_READY_TIMEOUT: {{ .Values.config.podReadyTimeout | quote }}
+EVICTION_TIMEOUT: {{ .Values.config.evictionTimeout | quote }}
+RESTART_MAX_WAIT: {{ .Values.config.restartMaxWait | quote }}
+PARTIAL_RESTART_POLICY: {{ .Values.config.partialRestartPolicy | quote }}
+RESTART_RETRIES: {{ .Values.config.restartRetries | quote }}
+RESTART_RETRY_BACKOFF: {{ .Values.config.restartRetryBackoff | quote }}
+NOTIFY_WEBHOOKS: {{ .Values.config.notifyWebhooks | quote }}
+ROLLBACK_BRANCH: {{ .Values.config.rollbackBranch | quote }}
+ROLLBACK_ON_FAILURE: {{ .Values.config.rollbackOnFailure | quote }}
+POLICIES_ENABLED: {{ .Values.config.policiesEnabled | quote }}
+POLICY_WORKERS: {{ .Values.config.policyWorkers | quote }}
+DRY_RUN: {{ .Values.config.dryRun | quote }}
+{{- end }}
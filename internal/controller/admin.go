@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Command is a manual operation requested through the admin API
+type Command string
+
+const (
+	// CommandCheck runs an update check now, applying an update if one is found
+	CommandCheck Command = "check"
+	// CommandUpdate runs steamcmd app_update and restarts targets even if no update was detected
+	CommandUpdate Command = "update"
+	// CommandValidate validates the installed files without restarting anything
+	CommandValidate Command = "validate"
+	// CommandPause stops automatic checks from applying updates
+	CommandPause Command = "pause"
+	// CommandResume lets automatic checks apply updates again
+	CommandResume Command = "resume"
+	// CommandRestart restarts every target workload without updating
+	CommandRestart Command = "restart"
+	// CommandRollback installs the rollback branch, restarts targets onto it and pauses updates
+	CommandRollback Command = "rollback"
+)
+
+// commandQueueSize is how many commands may wait for the main loop
+const commandQueueSize = 4
+
+// historySize is how many update results the status keeps
+const historySize = 20
+
+var (
+	// ErrUnknownCommand is returned for commands the controller does not support
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrCommandQueueFull is returned when too many commands are already waiting
+	ErrCommandQueueFull = errors.New("command queue is full")
+	// ErrUnknownPolicy is returned when a command names a policy that is not reconciled
+	ErrUnknownPolicy = errors.New("unknown policy")
+	// ErrUpdateInProgress is returned for commands that cannot run while an update is being verified
+	ErrUpdateInProgress = errors.New("an update is in progress")
+)
+
+// CommandResult records the outcome of the last manual command
+type CommandResult struct {
+	Command     Command    `json:"command"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// commandRequest is a command waiting for the main loop
+type commandRequest struct {
+	command     Command
+	requestedAt time.Time
+}
+
+// Status is a snapshot of the controller published for the admin API
+type Status struct {
+	Policy         string         `json:"policy,omitempty"`
+	App            string         `json:"app"`
+	AppID          string         `json:"appId"`
+	Branch         string         `json:"branch"`
+	InstalledBuild string         `json:"installedBuild,omitempty"`
+	InstalledAt    time.Time      `json:"installedAt,omitzero"`
+	LatestBuild    string         `json:"latestBuild,omitempty"`
+	Phase          UpdatePhase    `json:"phase"`
+	PhaseStartedAt time.Time      `json:"phaseStartedAt,omitzero"`
+	Paused         bool           `json:"paused"`
+	RolledBackFrom string         `json:"rolledBackFrom,omitempty"`
+	RollbackBranch string         `json:"rollbackBranch,omitempty"`
+	UpdateDeferred bool           `json:"updateDeferred"`
+	RetryCount     int            `json:"retryCount"`
+	LastCheck      time.Time      `json:"lastCheck,omitzero"`
+	LastCheckError string         `json:"lastCheckError,omitempty"`
+	LastResult     *UpdateResult  `json:"lastResult,omitempty"`
+	LastCommand    *CommandResult `json:"lastCommand,omitempty"`
+	DryRun         bool           `json:"dryRun"`
+	Plan           *DryRunPlan    `json:"plan,omitempty"`
+
+	// History lists recent update results, newest first, since the controller started
+	History []*UpdateResult  `json:"history"`
+	Targets []TargetWorkload `json:"targets"`
+}
+
+func validCommand(command Command) bool {
+	switch command {
+	case CommandCheck, CommandUpdate, CommandValidate, CommandPause, CommandResume, CommandRestart, CommandRollback:
+		return true
+	}
+	return false
+}
+
+// Statuses returns the controller's status snapshot
+func (uc *UpdateController) Statuses() []Status {
+	return []Status{uc.Status()}
+}
+
+// Status returns the last published status snapshot
+func (uc *UpdateController) Status() Status {
+	uc.statusMu.RLock()
+	defer uc.statusMu.RUnlock()
+	return uc.status
+}
+
+// Submit queues a command for the main loop; policy must be empty or name this controller's policy
+func (uc *UpdateController) Submit(policy string, command Command) error {
+	if policy != "" && policy != uc.currentConfig().PolicyName {
+		return fmt.Errorf("%w %q", ErrUnknownPolicy, policy)
+	}
+	return uc.submit(command)
+}
+
+func (uc *UpdateController) submit(command Command) error {
+	if !validCommand(command) {
+		return fmt.Errorf("%w %q", ErrUnknownCommand, command)
+	}
+
+	select {
+	case uc.commands <- commandRequest{command: command, requestedAt: time.Now()}:
+		klog.Infof("Queued %s command", command)
+		return nil
+	default:
+		return ErrCommandQueueFull
+	}
+}
+
+// runPendingCommands runs every queued command and reports whether any ran
+func (uc *UpdateController) runPendingCommands(ctx context.Context) bool {
+	ran := false
+	for {
+		select {
+		case req := <-uc.commands:
+			uc.runCommand(ctx, req)
+			ran = true
+		default:
+			return ran
+		}
+	}
+}
+
+// runCommand executes a manual command on the main loop
+func (uc *UpdateController) runCommand(ctx context.Context, req commandRequest) {
+	klog.Infof("Running %s command", req.command)
+
+	err := uc.commandAllowed(req.command)
+	if err == nil {
+		err = uc.executeCommand(ctx, req.command)
+	}
+
+	if err != nil {
+		klog.Errorf("%s command failed: %v", req.command, err)
+	}
+
+	finishedAt := time.Now()
+	result := &CommandResult{Command: req.command, RequestedAt: req.requestedAt, FinishedAt: &finishedAt}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	uc.lastCommand = result
+	uc.refreshTargets(ctx)
+	uc.publishStatus()
+}
+
+// executeCommand runs a single command
+func (uc *UpdateController) executeCommand(ctx context.Context, command Command) error {
+	switch command {
+	case CommandCheck:
+		return uc.performUpdateCheck(ctx)
+	case CommandUpdate:
+		// Refresh the latest build so the update has a target
+		if _, checkErr := uc.steamClient.CheckUpdate(ctx); checkErr != nil {
+			klog.Warningf("Failed to refresh latest build before forced update: %v", checkErr)
+		}
+		return uc.applyUpdate(ctx)
+	case CommandValidate:
+		return uc.validate(ctx)
+	case CommandPause:
+		uc.state.Paused = true
+		uc.saveState()
+		klog.Info("Automatic updates paused")
+	case CommandResume:
+		uc.state.Paused = false
+		// The next check may reinstall the build a rollback replaced
+		uc.state.RolledBackFrom = ""
+		uc.saveState()
+		klog.Info("Automatic updates resumed")
+	case CommandRestart:
+		_, err := uc.restartAllTargets(ctx)
+		return err
+	case CommandRollback:
+		return uc.rollback(ctx, "requested through the admin API")
+	}
+	return nil
+}
+
+// commandAllowed rejects commands that install or restart while an update's
+// log scan still runs alongside the main loop
+func (uc *UpdateController) commandAllowed(command Command) error {
+	if uc.state.Phase == PhaseIdle {
+		return nil
+	}
+	switch command {
+	case CommandUpdate, CommandValidate, CommandRestart, CommandRollback:
+		return fmt.Errorf("%w (%s)", ErrUpdateInProgress, uc.state.Phase)
+	}
+	return nil
+}
+
+// restartAllTargets restarts every target workload regardless of the build its pods run
+func (uc *UpdateController) restartAllTargets(ctx context.Context) (*RestartResult, error) {
+	selection, err := uc.selectTargetPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := uc.groupWorkloads(ctx, selection.Pods)
+	if len(workloads) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	result := uc.restartWorkloads(ctx, workloads)
+	result.Excluded = selection.Excluded
+	return result, result.err(uc.config.PartialRestartPolicy)
+}
+
+// recordHistory adds an update result to the front of the bounded history
+func (uc *UpdateController) recordHistory(result *UpdateResult) {
+	// A fresh slice keeps published snapshots from seeing later results
+	history := make([]*UpdateResult, 0, historySize)
+	history = append(history, result)
+	history = append(history, uc.history...)
+	if len(history) > historySize {
+		history = history[:historySize]
+	}
+	uc.history = history
+}
+
+// publishStatus snapshots the loop-owned state for readers on other goroutines
+func (uc *UpdateController) publishStatus() {
+	status := Status{
+		Policy:         uc.config.PolicyName,
+		App:            uc.config.SteamApp,
+		AppID:          uc.config.SteamAppID,
+		Branch:         uc.config.SteamBranch,
+		InstalledBuild: uc.state.InstalledBuild,
+		InstalledAt:    uc.state.InstalledAt,
+		LatestBuild:    uc.steamClient.LatestBuild(),
+		Phase:          uc.state.Phase,
+		PhaseStartedAt: uc.state.PhaseStartedAt,
+		Paused:         uc.state.Paused,
+		RolledBackFrom: uc.state.RolledBackFrom,
+		RollbackBranch: uc.config.RollbackBranch,
+		UpdateDeferred: uc.updateDeferred,
+		RetryCount:     uc.state.RetryCount,
+		LastCheck:      uc.lastCheck,
+		LastResult:     uc.lastResult,
+		LastCommand:    uc.lastCommand,
+		DryRun:         uc.config.DryRun,
+		Plan:           uc.plan,
+		History:        uc.history,
+		Targets:        uc.targets,
+	}
+	if uc.lastCheckErr != nil {
+		status.LastCheckError = uc.lastCheckErr.Error()
+	}
+
+	uc.statusMu.Lock()
+	uc.status = status
+	uc.statusMu.Unlock()
+}
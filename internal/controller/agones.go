@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+// agonesPollInterval is how often GameServer state is polled while waiting
+const agonesPollInterval = 10 * time.Second
+
+// gameServerGVR is the Agones GameServer resource
+var gameServerGVR = schema.GroupVersionResource{Group: "agones.dev", Version: "v1", Resource: "gameservers"}
+
+// restartGameServer replaces a standalone GameServer once it is no longer
+// allocated by deleting it and recreating it from its spec
+func (uc *UpdateController) restartGameServer(ctx context.Context, namespace, name string) error {
+	gs, err := uc.waitForGameServerReleased(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	replacement := newGameServerFrom(gs)
+
+	client := uc.dynamicClient.Resource(gameServerGVR).Namespace(namespace)
+	if err := client.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete gameserver: %w", err)
+	}
+
+	// A fixed name can only be reused once the old object is gone
+	if replacement.GetName() != "" {
+		if err := uc.waitForGameServerDeleted(ctx, namespace, name); err != nil {
+			return err
+		}
+	}
+
+	created, err := client.Create(ctx, replacement, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to recreate gameserver: %w", err)
+	}
+
+	klog.V(2).Infof("Recreated gameserver %s as %s", name, created.GetName())
+	return nil
+}
+
+// waitForGameServerReleased blocks until a GameServer is neither Allocated nor Reserved
+func (uc *UpdateController) waitForGameServerReleased(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	deadline := time.Now().Add(uc.config.AgonesAllocatedWait)
+
+	for {
+		gs, err := uc.dynamicClient.Resource(gameServerGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gameserver: %w", err)
+		}
+
+		state, _, _ := unstructured.NestedString(gs.Object, "status", "state")
+		if state != "Allocated" && state != "Reserved" {
+			return gs, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("gameserver %s still %s after %s", name, state, uc.config.AgonesAllocatedWait)
+		}
+
+		klog.Infof("GameServer %s is %s, waiting for it to return to Ready", name, state)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(agonesPollInterval):
+		}
+	}
+}
+
+// waitForGameServerDeleted blocks until a GameServer no longer exists
+func (uc *UpdateController) waitForGameServerDeleted(ctx context.Context, namespace, name string) error {
+	for {
+		_, err := uc.dynamicClient.Resource(gameServerGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get gameserver: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(agonesPollInterval):
+		}
+	}
+}
+
+// newGameServerFrom builds a fresh GameServer with the same metadata and spec
+func newGameServerFrom(gs *unstructured.Unstructured) *unstructured.Unstructured {
+	replacement := &unstructured.Unstructured{Object: map[string]any{}}
+	replacement.SetAPIVersion(gs.GetAPIVersion())
+	replacement.SetKind(gs.GetKind())
+	replacement.SetNamespace(gs.GetNamespace())
+	replacement.SetLabels(gs.GetLabels())
+	replacement.SetAnnotations(gs.GetAnnotations())
+
+	if generateName := gs.GetGenerateName(); generateName != "" {
+		replacement.SetGenerateName(generateName)
+	} else {
+		replacement.SetName(gs.GetName())
+	}
+
+	if spec, found, _ := unstructured.NestedMap(gs.Object, "spec"); found {
+		_ = unstructured.SetNestedMap(replacement.Object, spec, "spec")
+	}
+
+	return replacement
+}
@@ -0,0 +1,20 @@
+package controller
+
+// annotationPrefix namespaces the annotations read from target workloads
+const annotationPrefix = "updatecontroller.udl.tf/"
+
+const (
+	// policyAnnotation is auto, manual or skip
+	policyAnnotation = annotationPrefix + "policy"
+	// restartStrategyAnnotation overrides RESTART_STRATEGY for a single workload
+	restartStrategyAnnotation = annotationPrefix + "restart-strategy"
+	// priorityAnnotation orders restarts; higher priorities restart first
+	priorityAnnotation = annotationPrefix + "priority"
+	// maxWaitAnnotation overrides RESTART_MAX_WAIT for a single workload
+	maxWaitAnnotation = annotationPrefix + "max-wait"
+
+	// originalReplicasAnnotation records the replica count while a recreate is in progress
+	originalReplicasAnnotation = annotationPrefix + "original-replicas"
+	// recreatingLabel marks workloads scaled down by a recreate so they can be found after a crash
+	recreatingLabel = annotationPrefix + "recreating"
+)
@@ -1,9 +1,19 @@
 package controller
 
 import (
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
 )
 
 // Config holds the configuration for the UpdateController
@@ -12,51 +22,572 @@ type Config struct {
 	SteamCMDPath  string
 	SteamApp      string
 	SteamAppID    string
+	SteamBranch   string
 	GameMountPath string
 	UpdateScript  string
 	PodSelector   string
+	FieldSelector string
 	MaxRetries    int
 	RetryDelay    time.Duration
 	Namespace     string
+
+	// HTTPAddr is the listen address for metrics and probes
+	HTTPAddr string
+
+	// AdminToken is the bearer token for the admin API, which is disabled when empty
+	AdminToken string
+
+	// SteamCMDTimeout bounds a single steamcmd run; a run stuck past it fails the liveness probe
+	SteamCMDTimeout time.Duration
+
+	// StateFile persists update progress; relative paths are resolved against GameMountPath
+	StateFile string
+
+	// Namespaces are the target namespaces, defaulting to Namespace; NamespaceSelector adds matching namespaces
+	Namespaces            []string
+	NamespaceSelector     string
+	NamespacePodSelectors map[string]string
+
+	// LogScanWindow is how long restarted pods are watched for log rule matches; 0 disables scanning
+	LogScanWindow time.Duration
+	LogScanRules  []LogScanRule
+
+	// LogTriggerEnabled follows game server logs and checks for updates as soon as a server reports it is out of date
+	LogTriggerEnabled  bool
+	LogTriggerCooldown time.Duration
+
+	// AgonesAllocatedWait bounds how long a standalone Allocated GameServer is waited on before its restart fails
+	AgonesAllocatedWait time.Duration
+
+	// UnknownKindActions maps "Kind" or "Kind.group" of unsupported top-level owners to a restart action
+	UnknownKindActions map[string]KindAction
+
+	// RestartStrategy is the default strategy, overridable per workload with the restart-strategy annotation
+	RestartStrategy RestartStrategy
+	PodReadyTimeout time.Duration
+	EvictionTimeout time.Duration
+
+	// RestartMaxWait is how long to wait for each workload to become ready before restarting the next; 0 does not wait
+	RestartMaxWait time.Duration
+
+	// PartialRestartPolicy decides whether some failed workloads still count as a successful restart
+	PartialRestartPolicy PartialRestartPolicy
+	RestartRetries       int
+	RestartRetryBackoff  time.Duration
+
+	// MaintenanceWindows limit when updates and restarts run; empty means any time
+	MaintenanceWindows []MaintenanceWindow
+	Notifications      []NotificationSink
+
+	// RollbackBranch is a Steam beta branch holding the previous release, installed by a rollback
+	RollbackBranch string
+	// RollbackOnFailure rolls back automatically when the post-restart log scan finds failures
+	RollbackOnFailure bool
+
+	// PoliciesEnabled reconciles GameUpdatePolicy objects instead of the single environment-configured app
+	PoliciesEnabled bool
+	PolicyWorkers   int
+
+	// DryRun checks for updates but only plans steamcmd runs and Kubernetes changes instead of making them
+	DryRun bool
+
+	// PolicyName is the namespace/name of the GameUpdatePolicy a derived config belongs to
+	PolicyName string
+}
+
+// defaultLogScanRules catches the common SourceMod breakages after a game patch
+var defaultLogScanRules = []string{
+	`failure:Failed to load gamedata`,
+	`failure:\[SM\] Unable to load plugin`,
+	`warning:\[SM\] Unable to load extension`,
+	`warning:\[SM\] Exception reported`,
+}
+
+// ConfigError lists every problem found while loading the configuration
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// LoadConfig loads configuration from the optional YAML or JSON file at path,
+// overridden by environment variables, and validates it. Every malformed
+// value, unknown file key and inconsistent setting is reported in a
+// *ConfigError instead of falling back to defaults
+func LoadConfig(path string) (*Config, error) {
+	src := &configSource{used: make(map[string]bool)}
+	if path != "" {
+		if err := src.readFile(path); err != nil {
+			return nil, &ConfigError{Problems: []string{err.Error()}}
+		}
+	}
+
+	config := &Config{
+		CheckInterval: src.duration("CHECK_INTERVAL", 30*time.Minute),
+		SteamCMDPath:  src.string("STEAMCMD_PATH", "/home/steam/steamcmd"),
+		SteamApp:      src.string("STEAMAPP", "tf"),
+		SteamAppID:    src.string("STEAMAPPID", "232250"),
+		SteamBranch:   src.string("STEAM_BRANCH", "public"),
+		GameMountPath: src.string("GAME_MOUNT_PATH", "/tf"),
+		UpdateScript:  src.string("UPDATE_SCRIPT", "tf_update.txt"),
+		PodSelector:   src.string("POD_SELECTOR", "app=tf2-server"),
+		FieldSelector: src.string("FIELD_SELECTOR", ""),
+		MaxRetries:    src.int("MAX_RETRIES", 3),
+		RetryDelay:    src.duration("RETRY_DELAY", 5*time.Minute),
+		Namespace:     src.string("NAMESPACE", "default"),
+		StateFile:     src.string("STATE_FILE", ".updatecontroller-state.json"),
+		HTTPAddr:      src.string("HTTP_ADDR", ":8080"),
+		AdminToken:    src.string("ADMIN_TOKEN", ""),
+
+		SteamCMDTimeout: src.duration("STEAMCMD_TIMEOUT", 2*time.Hour),
+
+		Namespaces:            src.list("NAMESPACES"),
+		NamespaceSelector:     src.string("NAMESPACE_SELECTOR", ""),
+		NamespacePodSelectors: src.namespaceSelectors("NAMESPACE_POD_SELECTORS"),
+
+		LogScanWindow: src.duration("LOG_SCAN_WINDOW", 0),
+		LogScanRules:  src.logScanRules("LOG_SCAN_RULES", defaultLogScanRules),
+
+		LogTriggerEnabled:  src.bool("LOG_TRIGGER_ENABLED", false),
+		LogTriggerCooldown: src.duration("LOG_TRIGGER_COOLDOWN", 15*time.Minute),
+
+		AgonesAllocatedWait: src.duration("AGONES_ALLOCATED_WAIT", time.Hour),
+		UnknownKindActions:  src.kindActions("UNKNOWN_KIND_ACTIONS"),
+
+		RestartStrategy: src.restartStrategy("RESTART_STRATEGY", RestartStrategyRollout),
+		PodReadyTimeout: src.duration("POD_READY_TIMEOUT", 10*time.Minute),
+		EvictionTimeout: src.duration("EVICTION_TIMEOUT", 30*time.Minute),
+		RestartMaxWait:  src.duration("RESTART_MAX_WAIT", 0),
+
+		PartialRestartPolicy: src.partialRestartPolicy("PARTIAL_RESTART_POLICY", PartialRestartAll),
+		RestartRetries:       src.int("RESTART_RETRIES", 3),
+		RestartRetryBackoff:  src.duration("RESTART_RETRY_BACKOFF", 30*time.Second),
+
+		Notifications: src.webhooks("NOTIFY_WEBHOOKS"),
+
+		RollbackBranch:    src.string("ROLLBACK_BRANCH", ""),
+		RollbackOnFailure: src.bool("ROLLBACK_ON_FAILURE", false),
+
+		PoliciesEnabled: src.bool("POLICIES_ENABLED", false),
+		PolicyWorkers:   src.int("POLICY_WORKERS", 2),
+
+		DryRun: src.bool("DRY_RUN", false),
+	}
+
+	// NAMESPACE stays the single target unless a list or selector is given
+	if len(config.Namespaces) == 0 && config.NamespaceSelector == "" {
+		config.Namespaces = []string{config.Namespace}
+	}
+
+	problems := src.unknownKeys(path)
+	problems = append(problems, src.problems...)
+	problems = append(problems, config.validate()...)
+	problems = append(problems, config.validatePaths()...)
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+	return config, nil
 }
 
-// LoadConfig loads configuration from environment variables
-func LoadConfig() *Config {
-	return &Config{
-		CheckInterval: getEnvDuration("CHECK_INTERVAL", 30*time.Minute),
-		SteamCMDPath:  getEnv("STEAMCMD_PATH", "/home/steam/steamcmd"),
-		SteamApp:      getEnv("STEAMAPP", "tf"),
-		SteamAppID:    getEnv("STEAMAPPID", "232250"),
-		GameMountPath: getEnv("GAME_MOUNT_PATH", "/tf"),
-		UpdateScript:  getEnv("UPDATE_SCRIPT", "tf_update.txt"),
-		PodSelector:   getEnv("POD_SELECTOR", "app=tf2-server"),
-		MaxRetries:    getEnvInt("MAX_RETRIES", 3),
-		RetryDelay:    getEnvDuration("RETRY_DELAY", 5*time.Minute),
-		Namespace:     getEnv("NAMESPACE", "default"),
+// validate checks settings that are well-formed on their own but unusable together
+func (c *Config) validate() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for key, value := range map[string]time.Duration{
+		"CHECK_INTERVAL":        c.CheckInterval,
+		"RETRY_DELAY":           c.RetryDelay,
+		"POD_READY_TIMEOUT":     c.PodReadyTimeout,
+		"EVICTION_TIMEOUT":      c.EvictionTimeout,
+		"AGONES_ALLOCATED_WAIT": c.AgonesAllocatedWait,
+		"RESTART_RETRY_BACKOFF": c.RestartRetryBackoff,
+	} {
+		if value <= 0 {
+			add("%s must be positive, got %s", key, value)
+		}
+	}
+	for key, value := range map[string]time.Duration{
+		"STEAMCMD_TIMEOUT":     c.SteamCMDTimeout,
+		"LOG_SCAN_WINDOW":      c.LogScanWindow,
+		"LOG_TRIGGER_COOLDOWN": c.LogTriggerCooldown,
+		"RESTART_MAX_WAIT":     c.RestartMaxWait,
+	} {
+		if value < 0 {
+			add("%s must not be negative, got %s", key, value)
+		}
+	}
+
+	if c.MaxRetries < 1 {
+		add("MAX_RETRIES must be at least 1, got %d", c.MaxRetries)
 	}
+	if c.RestartRetries < 0 {
+		add("RESTART_RETRIES must not be negative, got %d", c.RestartRetries)
+	}
+	if c.PolicyWorkers < 1 {
+		add("POLICY_WORKERS must be at least 1, got %d", c.PolicyWorkers)
+	}
+
+	if c.StateFile == "" {
+		add("STATE_FILE must not be empty")
+	}
+	if c.RollbackOnFailure && c.RollbackBranch == "" {
+		add("ROLLBACK_ON_FAILURE requires ROLLBACK_BRANCH")
+	}
+	if _, _, err := net.SplitHostPort(c.HTTPAddr); err != nil {
+		add("HTTP_ADDR %q is not a host:port address: %v", c.HTTPAddr, err)
+	}
+
+	for namespace, selector := range c.NamespacePodSelectors {
+		if _, err := labels.Parse(selector); err != nil {
+			add("NAMESPACE_POD_SELECTORS selector %q for namespace %s is not a valid label selector: %v", selector, namespace, err)
+		}
+	}
+
+	problems = append(problems, c.validatePolicy()...)
+	sort.Strings(problems)
+	return problems
+}
+
+// validatePolicy checks the settings a GameUpdatePolicy can override
+func (c *Config) validatePolicy() []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.RetryDelay > 0 && c.CheckInterval > 0 && c.RetryDelay >= c.CheckInterval {
+		add("RETRY_DELAY (%s) must be shorter than CHECK_INTERVAL (%s)", c.RetryDelay, c.CheckInterval)
+	}
+	if _, err := strconv.ParseUint(c.SteamAppID, 10, 32); err != nil {
+		add("STEAMAPPID must be a numeric app ID, got %q", c.SteamAppID)
+	}
+	if c.RollbackBranch != "" && c.RollbackBranch == c.SteamBranch {
+		add("ROLLBACK_BRANCH must differ from STEAM_BRANCH %q", c.SteamBranch)
+	}
+	if _, err := labels.Parse(c.PodSelector); err != nil {
+		add("POD_SELECTOR %q is not a valid label selector: %v", c.PodSelector, err)
+	}
+	if _, err := fields.ParseSelector(c.FieldSelector); err != nil {
+		add("FIELD_SELECTOR %q is not a valid field selector: %v", c.FieldSelector, err)
+	}
+	if _, err := labels.Parse(c.NamespaceSelector); err != nil {
+		add("NAMESPACE_SELECTOR %q is not a valid label selector: %v", c.NamespaceSelector, err)
+	}
+	return problems
+}
+
+// validatePaths checks that the host directories the controller works in exist
+func (c *Config) validatePaths() []string {
+	var problems []string
+	if info, err := os.Stat(c.SteamCMDPath); err != nil || !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("STEAMCMD_PATH %s is not an existing directory", c.SteamCMDPath))
+	}
+	// Policies bring their own volumes
+	if !c.PoliciesEnabled {
+		if info, err := os.Stat(c.GameMountPath); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("GAME_MOUNT_PATH %s is not an existing directory", c.GameMountPath))
+		}
+	}
+	return problems
+}
+
+// configSource resolves settings from the environment, falling back to the
+// config file, and records every value it cannot parse
+type configSource struct {
+	// file holds the config file's values, flattened to their environment form
+	file map[string]string
+	used map[string]bool
+
+	problems []string
+}
+
+// listSeparators are the separators of list settings in their environment form
+var listSeparators = map[string]string{
+	"NAMESPACE_POD_SELECTORS": ";",
+	"LOG_SCAN_RULES":          "\n",
 }
 
-func getEnv(key, defaultValue string) string {
+// readFile loads a YAML or JSON file whose keys are the environment variable names
+func (s *configSource) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	s.file = make(map[string]string, len(raw))
+	for key, value := range raw {
+		flat, err := flattenConfigValue(value, listSeparators[key])
+		if err != nil {
+			s.problems = append(s.problems, fmt.Sprintf("%s in %s: %v", key, path, err))
+			continue
+		}
+		s.file[key] = flat
+	}
+	return nil
+}
+
+// flattenConfigValue turns a file value into the string its environment variable would hold;
+// lists are joined with sep and maps become key=value entries
+func flattenConfigValue(value any, sep string) (string, error) {
+	if sep == "" {
+		sep = ","
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		entries := make([]string, 0, len(v))
+		for _, item := range v {
+			entry, err := flattenConfigValue(item, sep)
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, entry)
+		}
+		return strings.Join(entries, sep), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, 0, len(v))
+		for _, key := range keys {
+			entry, err := flattenConfigValue(v[key], sep)
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, key+"="+entry)
+		}
+		return strings.Join(entries, sep), nil
+	}
+	return "", fmt.Errorf("unsupported value of type %T", value)
+}
+
+// unknownKeys reports config file keys that no setting read
+func (s *configSource) unknownKeys(path string) []string {
+	var problems []string
+	for key := range s.file {
+		if !s.used[key] {
+			problems = append(problems, fmt.Sprintf("unknown key %s in %s", key, path))
+		}
+	}
+	return problems
+}
+
+// lookup returns the environment value of key, or else its config file value
+func (s *configSource) lookup(key string) (string, bool) {
+	s.used[key] = true
 	if value := os.Getenv(key); value != "" {
+		return value, true
+	}
+	value, ok := s.file[key]
+	return value, ok && value != ""
+}
+
+func (s *configSource) invalid(key, value, format string, args ...any) {
+	s.problems = append(s.problems, fmt.Sprintf("%s=%q: %s", key, value, fmt.Sprintf(format, args...)))
+}
+
+func (s *configSource) string(key, defaultValue string) string {
+	if value, ok := s.lookup(key); ok {
 		return value
 	}
 	return defaultValue
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+func (s *configSource) int(key string, defaultValue int) int {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		s.invalid(key, value, "not an integer")
+		return defaultValue
+	}
+	return intValue
+}
+
+func (s *configSource) bool(key string, defaultValue bool) bool {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		s.invalid(key, value, "not a boolean, use true or false")
+		return defaultValue
+	}
+	return boolValue
+}
+
+func (s *configSource) duration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		s.invalid(key, value, "not a duration, use units like 90s, 30m or 2h")
+		return defaultValue
+	}
+	return duration
+}
+
+// list parses a comma separated list, dropping empty entries
+func (s *configSource) list(key string) []string {
+	value, _ := s.lookup(key)
+
+	var values []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			values = append(values, entry)
 		}
 	}
-	return defaultValue
+	return values
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+// namespaceSelectors parses semicolon separated "namespace=selector" overrides
+func (s *configSource) namespaceSelectors(key string) map[string]string {
+	value, _ := s.lookup(key)
+
+	selectors := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
 		}
+
+		namespace, selector, found := strings.Cut(entry, "=")
+		if !found || strings.TrimSpace(namespace) == "" {
+			s.invalid(key, entry, "not in namespace=selector form")
+			continue
+		}
+		selectors[strings.TrimSpace(namespace)] = strings.TrimSpace(selector)
+	}
+	return selectors
+}
+
+func (s *configSource) restartStrategy(key string, defaultValue RestartStrategy) RestartStrategy {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	strategy, valid := parseRestartStrategy(value)
+	if !valid {
+		s.invalid(key, value, "must be rollout, pod-delete, evict or recreate")
+		return defaultValue
+	}
+	return strategy
+}
+
+func (s *configSource) partialRestartPolicy(key string, defaultValue PartialRestartPolicy) PartialRestartPolicy {
+	value, ok := s.lookup(key)
+	if !ok {
+		return defaultValue
+	}
+	switch policy := PartialRestartPolicy(value); policy {
+	case PartialRestartAll, PartialRestartAny:
+		return policy
 	}
+	s.invalid(key, value, "must be all or any")
 	return defaultValue
 }
+
+// logScanRules parses newline separated "severity:regex" rules
+func (s *configSource) logScanRules(key string, defaultValue []string) []LogScanRule {
+	if value, ok := s.lookup(key); ok {
+		rules, err := parseLogScanRules(strings.Split(value, "\n"))
+		if err == nil {
+			return rules
+		}
+		s.invalid(key, value, "%v", err)
+	}
+
+	rules, _ := parseLogScanRules(defaultValue)
+	return rules
+}
+
+func parseLogScanRules(lines []string) ([]LogScanRule, error) {
+	var rules []LogScanRule
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		severity, expr, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("rule %q is not in severity:regex form", line)
+		}
+
+		sev := LogSeverity(strings.ToLower(strings.TrimSpace(severity)))
+		if sev != LogSeverityWarning && sev != LogSeverityFailure {
+			return nil, fmt.Errorf("rule %q has unknown severity %q", line, severity)
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q has invalid regex: %w", line, err)
+		}
+
+		rules = append(rules, LogScanRule{Severity: sev, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// kindActions parses comma separated "Kind.group=action" pairs
+func (s *configSource) kindActions(key string) map[string]KindAction {
+	actions := make(map[string]KindAction)
+	value, ok := s.lookup(key)
+	if !ok {
+		return actions
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		kind, action, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || kind == "" {
+			s.invalid(key, pair, "not in Kind[.group]=action form")
+			continue
+		}
+
+		switch a := KindAction(strings.TrimSpace(action)); a {
+		case KindActionAnnotate, KindActionDeletePods, KindActionSkip:
+			actions[strings.TrimSpace(kind)] = a
+		default:
+			s.invalid(key, pair, "unknown action %q, must be annotate, delete-pods or skip", action)
+		}
+	}
+	return actions
+}
+
+// webhooks parses a comma separated list of webhook URLs into notification sinks
+func (s *configSource) webhooks(key string) []NotificationSink {
+	var sinks []NotificationSink
+	for _, raw := range s.list(key) {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			s.invalid(key, raw, "not an http or https URL")
+			continue
+		}
+		sinks = append(sinks, NotificationSink{Type: NotificationSinkWebhook, URL: raw})
+	}
+	return sinks
+}
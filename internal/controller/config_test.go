@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configEnv points the install paths at fresh directories and sets env, so
+// the host environment cannot leak into a test
+func configEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	t.Setenv("STEAMCMD_PATH", t.TempDir())
+	t.Setenv("GAME_MOUNT_PATH", t.TempDir())
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+// configFile writes content to a config file named name and returns its path, or "" without content
+func configFile(t *testing.T, name, content string) string {
+	t.Helper()
+	if content == "" {
+		return ""
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigSources(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		field func(*Config) any
+		want  any
+	}{
+		{
+			name:  "default",
+			field: func(c *Config) any { return c.CheckInterval },
+			want:  30 * time.Minute,
+		},
+		{
+			name:  "file only",
+			file:  "CHECK_INTERVAL: 1h\n",
+			field: func(c *Config) any { return c.CheckInterval },
+			want:  time.Hour,
+		},
+		{
+			name:  "environment overrides file",
+			file:  "CHECK_INTERVAL: 1h\n",
+			env:   map[string]string{"CHECK_INTERVAL": "2h"},
+			field: func(c *Config) any { return c.CheckInterval },
+			want:  2 * time.Hour,
+		},
+		{
+			name:  "empty environment falls back to file",
+			file:  "STEAM_BRANCH: prerelease\n",
+			env:   map[string]string{"STEAM_BRANCH": ""},
+			field: func(c *Config) any { return c.SteamBranch },
+			want:  "prerelease",
+		},
+		{
+			name:  "environment without file",
+			env:   map[string]string{"MAX_RETRIES": "7"},
+			field: func(c *Config) any { return c.MaxRetries },
+			want:  7,
+		},
+		{
+			name:  "file number and bool",
+			file:  "MAX_RETRIES: 5\nDRY_RUN: true\n",
+			field: func(c *Config) any { return []any{c.MaxRetries, c.DryRun} },
+			want:  []any{5, true},
+		},
+		{
+			name:  "file list",
+			file:  "NAMESPACES:\n  - game-servers\n  - staging\n",
+			field: func(c *Config) any { return c.Namespaces },
+			want:  []string{"game-servers", "staging"},
+		},
+		{
+			name:  "file map",
+			file:  "NAMESPACE_POD_SELECTORS:\n  staging: app=tf2-test\n  game-servers: app=tf2-server\n",
+			field: func(c *Config) any { return c.NamespacePodSelectors },
+			want:  map[string]string{"staging": "app=tf2-test", "game-servers": "app=tf2-server"},
+		},
+		{
+			name:  "NAMESPACE is the default target",
+			env:   map[string]string{"NAMESPACE": "game-servers"},
+			field: func(c *Config) any { return c.Namespaces },
+			want:  []string{"game-servers"},
+		},
+		{
+			name:  "JSON file",
+			file:  `{"CHECK_INTERVAL": "45m", "STEAM_BRANCH": "prerelease"}`,
+			field: func(c *Config) any { return []any{c.CheckInterval, c.SteamBranch} },
+			want:  []any{45 * time.Minute, "prerelease"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configEnv(t, tt.env)
+			config, err := LoadConfig(configFile(t, "config.yaml", tt.file))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if got := tt.field(config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "unknown file key",
+			file: "CHECK_INTERVALL: 1h\n",
+			want: []string{"unknown key CHECK_INTERVALL"},
+		},
+		{
+			name: "unparsable file",
+			file: "CHECK_INTERVAL: [1h\n",
+			want: []string{"failed to parse config file"},
+		},
+		{
+			name: "malformed values",
+			env:  map[string]string{"CHECK_INTERVAL": "often", "MAX_RETRIES": "three", "DRY_RUN": "maybe"},
+			want: []string{`CHECK_INTERVAL="often": not a duration`, `MAX_RETRIES="three": not an integer`, `DRY_RUN="maybe": not a boolean`},
+		},
+		{
+			name: "malformed file value",
+			file: "RESTART_STRATEGY: bounce\n",
+			want: []string{`RESTART_STRATEGY="bounce": must be rollout, pod-delete, evict or recreate`},
+		},
+		{
+			name: "retry delay not shorter than check interval",
+			env:  map[string]string{"CHECK_INTERVAL": "5m", "RETRY_DELAY": "5m"},
+			want: []string{"RETRY_DELAY (5m0s) must be shorter than CHECK_INTERVAL (5m0s)"},
+		},
+		{
+			name: "cross-field rule across file and environment",
+			file: "RETRY_DELAY: 10m\n",
+			env:  map[string]string{"CHECK_INTERVAL": "5m"},
+			want: []string{"RETRY_DELAY (10m0s) must be shorter than CHECK_INTERVAL (5m0s)"},
+		},
+		{
+			name: "non-positive durations",
+			env:  map[string]string{"CHECK_INTERVAL": "0s", "LOG_SCAN_WINDOW": "-1m"},
+			want: []string{"CHECK_INTERVAL must be positive", "LOG_SCAN_WINDOW must not be negative"},
+		},
+		{
+			name: "counts out of range",
+			env:  map[string]string{"MAX_RETRIES": "0", "RESTART_RETRIES": "-1", "POLICY_WORKERS": "0"},
+			want: []string{"MAX_RETRIES must be at least 1", "RESTART_RETRIES must not be negative", "POLICY_WORKERS must be at least 1"},
+		},
+		{
+			name: "rollback on failure without branch",
+			env:  map[string]string{"ROLLBACK_ON_FAILURE": "true", "ROLLBACK_BRANCH": ""},
+			want: []string{"ROLLBACK_ON_FAILURE requires ROLLBACK_BRANCH"},
+		},
+		{
+			name: "rollback branch is the tracked branch",
+			env:  map[string]string{"ROLLBACK_BRANCH": "public", "STEAM_BRANCH": ""},
+			want: []string{`ROLLBACK_BRANCH must differ from STEAM_BRANCH "public"`},
+		},
+		{
+			name: "invalid app ID and selectors",
+			env:  map[string]string{"STEAMAPPID": "tf2", "POD_SELECTOR": "app in (", "FIELD_SELECTOR": "status.phase", "HTTP_ADDR": "8080"},
+			want: []string{"STEAMAPPID must be a numeric app ID", "POD_SELECTOR", "FIELD_SELECTOR", "HTTP_ADDR"},
+		},
+		{
+			name: "missing game volume",
+			env:  map[string]string{"GAME_MOUNT_PATH": "/nonexistent/tf"},
+			want: []string{"GAME_MOUNT_PATH /nonexistent/tf is not an existing directory"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configEnv(t, tt.env)
+			_, err := LoadConfig(configFile(t, "config.yaml", tt.file))
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("LoadConfig() error = %v, want a *ConfigError", err)
+			}
+			for _, want := range tt.want {
+				if !containsProblem(configErr.Problems, want) {
+					t.Errorf("LoadConfig() problems = %q, want one containing %q", configErr.Problems, want)
+				}
+			}
+		})
+	}
+}
+
+func containsProblem(problems []string, want string) bool {
+	for _, problem := range problems {
+		if strings.Contains(problem, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidatePolicyMode(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want int
+	}{
+		{"single app needs the game volume", map[string]string{"GAME_MOUNT_PATH": "/nonexistent/tf"}, 1},
+		{"policies bring their own volumes", map[string]string{"GAME_MOUNT_PATH": "/nonexistent/tf", "POLICIES_ENABLED": "true"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configEnv(t, tt.env)
+			_, err := LoadConfig("")
+
+			var configErr *ConfigError
+			switch {
+			case tt.want == 0 && err != nil:
+				t.Fatalf("LoadConfig() error = %v", err)
+			case tt.want > 0 && !errors.As(err, &configErr):
+				t.Fatalf("LoadConfig() error = %v, want a *ConfigError", err)
+			case tt.want > 0 && len(configErr.Problems) != tt.want:
+				t.Errorf("LoadConfig() problems = %q, want %d", configErr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlattenConfigValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		sep   string
+		want  string
+	}{
+		{"nil", nil, "", ""},
+		{"string", "app=tf2-server", "", "app=tf2-server"},
+		{"bool", true, "", "true"},
+		{"integer", float64(3), "", "3"},
+		{"fraction", 1.5, "", "1.5"},
+		{"list", []any{"game-servers", "staging"}, "", "game-servers,staging"},
+		{"list with separator", []any{"failure:Segmentation fault", "warning:\\[SM\\]"}, "\n", "failure:Segmentation fault\nwarning:\\[SM\\]"},
+		{"map sorted by key", map[string]any{"staging": "app=b", "game-servers": "app=a"}, ";", "game-servers=app=a;staging=app=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := flattenConfigValue(tt.value, tt.sep)
+			if err != nil {
+				t.Fatalf("flattenConfigValue() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("flattenConfigValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := flattenConfigValue(struct{}{}, ""); err == nil {
+		t.Error("flattenConfigValue() of an unsupported type succeeded")
+	}
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// recovery0x6 describes the recovery ApplyUpdate runs when steamcmd reports a 0x6 error state
+const recovery0x6 = "if app_update reports a 0x6 error state, the steamapps directory is deleted and app_update is retried once"
+
+// DryRunPlan describes what the controller would have done in place of
+// running steamcmd or changing Kubernetes objects
+type DryRunPlan struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Trigger is what produced the plan: update, validate or restart
+	Trigger        string `json:"trigger"`
+	InstalledBuild string `json:"installedBuild,omitempty"`
+	TargetBuild    string `json:"targetBuild,omitempty"`
+	// Steps are the steamcmd runs, in order
+	Steps []string `json:"steps,omitempty"`
+	// Recovery lists recovery that would run, or might run depending on steamcmd's result
+	Recovery []string `json:"recovery,omitempty"`
+	// Restart lists the workloads in the order they would be restarted
+	Restart  []*WorkloadPlan `json:"restart,omitempty"`
+	Excluded []ExcludedPod   `json:"excluded,omitempty"`
+}
+
+// dryRunSkip records a startup recovery step skipped in dry-run mode; it is
+// repeated in every plan since it stays pending
+func (uc *UpdateController) dryRunSkip(format string, args ...any) {
+	step := fmt.Sprintf(format, args...)
+	klog.Infof("[dry-run] Would %s", step)
+	uc.pendingRecovery = append(uc.pendingRecovery, step)
+}
+
+// recordPlan stores and logs a dry-run plan
+func (uc *UpdateController) recordPlan(plan *DryRunPlan) {
+	plan.GeneratedAt = time.Now()
+	plan.InstalledBuild = uc.state.InstalledBuild
+	plan.Recovery = append(append([]string{}, uc.pendingRecovery...), plan.Recovery...)
+	uc.plan = plan
+
+	target := ""
+	if plan.TargetBuild != "" {
+		target = " to build " + plan.TargetBuild
+	}
+	klog.Infof("[dry-run] Plan for %s%s (installed build %q):", plan.Trigger, target, plan.InstalledBuild)
+	for i, step := range plan.Steps {
+		klog.Infof("[dry-run]   step %d: %s", i+1, step)
+	}
+	for _, recovery := range plan.Recovery {
+		klog.Infof("[dry-run]   recovery: %s", recovery)
+	}
+	for i, restart := range plan.Restart {
+		klog.Infof("[dry-run]   restart %d: %s", i+1, restart)
+	}
+	for _, excluded := range plan.Excluded {
+		klog.Infof("[dry-run]   excluded: %s/%s (%s)", excluded.Namespace, excluded.Name, excluded.Reason)
+	}
+}
+
+// Plan returns the last dry-run plan, nil when none was made
+func (uc *UpdateController) Plan() *DryRunPlan {
+	return uc.plan
+}
+
+// planUpdate records what applyUpdate would do: the build to install, the
+// steamcmd runs with their possible recovery, and the resulting restart order
+func (uc *UpdateController) planUpdate(ctx context.Context) error {
+	plan := &DryRunPlan{
+		Trigger:     "update",
+		TargetBuild: uc.steamClient.LatestBuild(),
+		Steps: []string{
+			uc.steamClient.DescribeUpdate(false),
+			uc.steamClient.DescribeUpdate(true),
+		},
+		Recovery: []string{
+			recovery0x6,
+			fmt.Sprintf("a failed download or validation is retried after %s, up to %d attempts", uc.config.RetryDelay, uc.config.MaxRetries),
+		},
+	}
+
+	if _, found, err := uc.steamClient.InstallState(); err == nil && !found {
+		plan.Recovery = append(plan.Recovery, "no app manifest on the volume, app_update performs a full initial install")
+	}
+
+	// Every current target pod predates the install and would be restarted
+	selection, err := uc.selectTargetPods(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	plan.Restart = uc.planRestarts(uc.groupWorkloads(ctx, selection.Pods))
+	plan.Excluded = selection.Excluded
+
+	uc.recordPlan(plan)
+	return nil
+}
+
+// validate runs steamcmd validation, or plans it in dry-run mode
+func (uc *UpdateController) validate(ctx context.Context) error {
+	if uc.config.DryRun {
+		uc.recordPlan(&DryRunPlan{
+			Trigger: "validate",
+			Steps:   []string{uc.steamClient.DescribeUpdate(true)},
+		})
+		return nil
+	}
+	return uc.steamClient.ValidateUpdate(ctx)
+}
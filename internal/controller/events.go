@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// eventComponent is the source reported on emitted Events
+const eventComponent = "update-controller"
+
+// Event reasons for update and restart stages
+const (
+	EventUpdateDetected      = "UpdateDetected"
+	EventUpdateDeferred      = "UpdateDeferred"
+	EventDownloadStarted     = "DownloadStarted"
+	EventDownloadFinished    = "DownloadFinished"
+	EventDownloadFailed      = "DownloadFailed"
+	EventValidationSucceeded = "ValidationSucceeded"
+	EventValidationFailed    = "ValidationFailed"
+	EventStateRecovery       = "StateRecovery"
+	EventInstallRepair       = "InstallRepair"
+	EventInstallRepaired     = "InstallRepaired"
+	EventInstallRepairFailed = "InstallRepairFailed"
+	EventRestartInitiated    = "RestartInitiated"
+	EventRestartCompleted    = "RestartCompleted"
+	EventRestartFailed       = "RestartFailed"
+	EventRestartSkipped      = "RestartSkipped"
+	EventUpdateSucceeded     = "UpdateSucceeded"
+	EventUpdateFailed        = "UpdateFailed"
+	EventRollbackStarted     = "RollbackStarted"
+	EventRollbackSucceeded   = "RollbackSucceeded"
+	EventRollbackFailed      = "RollbackFailed"
+)
+
+// newEventBroadcaster creates a broadcaster writing Events through the core API
+func newEventBroadcaster(clientset kubernetes.Interface) record.EventBroadcaster {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	return broadcaster
+}
+
+func newEventRecorder(broadcaster record.EventBroadcaster) record.EventRecorder {
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent})
+}
+
+// controllerPodReference returns the controller's own pod from the downward
+// API POD_NAME and POD_NAMESPACE variables, or nil when they are not set
+func controllerPodReference(ctx context.Context, clientset kubernetes.Interface) *corev1.ObjectReference {
+	name, namespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if name == "" || namespace == "" {
+		klog.Info("POD_NAME or POD_NAMESPACE not set, update events are only recorded on workloads")
+		return nil
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Failed to get controller pod %s/%s for events: %v", namespace, name, err)
+		return nil
+	}
+
+	return &corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name, UID: pod.UID}
+}
+
+// policyReference returns the reference update events are recorded on in policy mode
+func policyReference(policy *GameUpdatePolicy) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: gameUpdatePolicyGVR.GroupVersion().String(),
+		Kind:       "GameUpdatePolicy",
+		Namespace:  policy.Namespace,
+		Name:       policy.Name,
+		UID:        policy.UID,
+	}
+}
+
+// controllerEvent records an Event on the controller-owned object; dry-run mode writes none
+func (uc *UpdateController) controllerEvent(eventType, reason, messageFmt string, args ...any) {
+	if uc.recorder == nil || uc.eventTarget == nil || uc.config.DryRun {
+		return
+	}
+	uc.recorder.Eventf(uc.eventTarget, eventType, reason, messageFmt, args...)
+}
+
+// workloadEvent records an Event on a restarted workload; dry-run mode writes none
+func (uc *UpdateController) workloadEvent(w *workload, eventType, reason, messageFmt string, args ...any) {
+	if uc.recorder == nil || uc.config.DryRun {
+		return
+	}
+	ref := &corev1.ObjectReference{APIVersion: w.APIVersion, Kind: w.Kind, Namespace: w.Namespace, Name: w.Name, UID: w.UID}
+	uc.recorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
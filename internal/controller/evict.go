@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// evictPods evicts a workload's pods through the Eviction API so
+// PodDisruptionBudgets are honoured, and reports pods that could not be
+// evicted before EVICTION_TIMEOUT
+func (uc *UpdateController) evictPods(ctx context.Context, w *workload) error {
+	deadline := time.Now().Add(uc.config.EvictionTimeout)
+
+	var notEvicted []string
+	for _, pod := range w.Pods {
+		if err := uc.evictPod(ctx, pod, deadline); err != nil {
+			klog.Warningf("Could not evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			notEvicted = append(notEvicted, fmt.Sprintf("%s (%v)", pod.Name, err))
+		}
+	}
+
+	if len(notEvicted) > 0 {
+		return fmt.Errorf("%d of %d pods could not be evicted: %s", len(notEvicted), len(w.Pods), strings.Join(notEvicted, "; "))
+	}
+	return nil
+}
+
+// evictPod requests eviction of a single pod, backing off while the API
+// answers 429 because the eviction would violate a PodDisruptionBudget
+func (uc *UpdateController) evictPod(ctx context.Context, pod *corev1.Pod, deadline time.Time) error {
+	backoff := wait.Backoff{
+		Duration: 5 * time.Second,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      time.Minute,
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	}
+
+	for {
+		err := uc.clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil:
+			klog.V(2).Infof("Evicted pod %s/%s", pod.Namespace, pod.Name)
+			return nil
+		case apierrors.IsNotFound(err):
+			return nil
+		case !apierrors.IsTooManyRequests(err):
+			return fmt.Errorf("eviction failed: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("still blocked by a PodDisruptionBudget after %s: %w", uc.config.EvictionTimeout, err)
+		}
+
+		delay := backoff.Step()
+		klog.Infof("Eviction of pod %s/%s would violate a PodDisruptionBudget, retrying in %s", pod.Namespace, pod.Name, delay.Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
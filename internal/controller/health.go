@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// stageGrace is how long a steamcmd run may overrun its deadline before the controller is considered wedged
+const stageGrace = time.Minute
+
+// heartbeat records what the main loop is doing for the liveness probe
+type heartbeat struct {
+	mu sync.Mutex
+
+	// beat is the last time the loop went idle or picked up work
+	beat    time.Time
+	working bool
+
+	// stage is the running steamcmd stage and the time it should have been killed by
+	stage    string
+	deadline time.Time
+}
+
+// idle records that the loop is waiting for its next tick
+func (h *heartbeat) idle() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beat = time.Now()
+	h.working = false
+}
+
+// work records that the loop picked up work
+func (h *heartbeat) work() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beat = time.Now()
+	h.working = true
+}
+
+func (h *heartbeat) startStage(stage string, deadline time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stage = stage
+	h.deadline = deadline
+}
+
+func (h *heartbeat) endStage() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stage = ""
+	h.deadline = time.Time{}
+}
+
+// check fails when a steamcmd run is stuck past its deadline, or when the
+// idle loop has not ticked within maxIdle; maxIdle 0 skips the tick check
+func (h *heartbeat) check(maxIdle time.Duration) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if h.stage != "" && !h.deadline.IsZero() && now.After(h.deadline.Add(stageGrace)) {
+		return fmt.Errorf("steamcmd %s is %s past its deadline", h.stage, now.Sub(h.deadline).Round(time.Second))
+	}
+
+	if maxIdle > 0 && !h.working && !h.beat.IsZero() && now.Sub(h.beat) > maxIdle {
+		return fmt.Errorf("main loop has not ticked for %s", now.Sub(h.beat).Round(time.Second))
+	}
+
+	return nil
+}
+
+// Healthy reports whether the main loop is alive and not wedged inside steamcmd
+func (uc *UpdateController) Healthy() error {
+	return uc.heartbeat.check(uc.currentConfig().CheckInterval + stageGrace)
+}
+
+// Ready reports whether the controller can do its work: the Kubernetes API
+// is reachable, the game volume is writable and steamcmd is installed
+func (uc *UpdateController) Ready(ctx context.Context) error {
+	if err := checkAPIServer(ctx, uc.clientset); err != nil {
+		return err
+	}
+	return checkInstallPaths(uc.currentConfig())
+}
+
+// checkAPIServer verifies the Kubernetes API answers
+func checkAPIServer(ctx context.Context, clientset kubernetes.Interface) error {
+	if err := clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("kubernetes API unreachable: %w", err)
+	}
+	return nil
+}
+
+// checkInstallPaths verifies the game volume is writable and steamcmd exists
+func checkInstallPaths(config *Config) error {
+	info, err := os.Stat(config.GameMountPath)
+	if err != nil {
+		return fmt.Errorf("game volume not mounted: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("game mount path %s is not a directory", config.GameMountPath)
+	}
+
+	probe, err := os.CreateTemp(config.GameMountPath, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("game volume not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	steamcmd := filepath.Join(config.SteamCMDPath, "steamcmd.sh")
+	info, err = os.Stat(steamcmd)
+	if err != nil {
+		return fmt.Errorf("steamcmd not found: %w", err)
+	}
+	if info.Mode()&0111 == 0 {
+		return fmt.Errorf("steamcmd at %s is not executable", steamcmd)
+	}
+
+	return nil
+}
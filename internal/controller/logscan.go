@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// logScanPollInterval is how often restarted pods are re-read during the scan window
+const logScanPollInterval = 15 * time.Second
+
+// LogSeverity classifies a log rule match
+type LogSeverity string
+
+const (
+	LogSeverityWarning LogSeverity = "warning"
+	LogSeverityFailure LogSeverity = "failure"
+)
+
+// LogScanRule is a regex evaluated against the logs of restarted pods
+type LogScanRule struct {
+	Severity LogSeverity
+	Pattern  *regexp.Regexp
+}
+
+// LogFinding records a single log line that matched a scan rule
+type LogFinding struct {
+	Namespace string      `json:"namespace"`
+	Pod       string      `json:"pod"`
+	Container string      `json:"container"`
+	Severity  LogSeverity `json:"severity"`
+	Rule      string      `json:"rule"`
+	Line      string      `json:"line"`
+}
+
+func (f LogFinding) String() string {
+	return fmt.Sprintf("[%s] %s/%s (%s) matched %q: %s", f.Severity, f.Namespace, f.Pod, f.Container, f.Rule, f.Line)
+}
+
+// scanRestartedPods follows the logs of pods started after restartedAt for the
+// configured window and returns every line matching a scan rule
+func (uc *UpdateController) scanRestartedPods(ctx context.Context, restartedAt time.Time) []LogFinding {
+	// The scan may run off the main loop, so it keeps to the config it started with
+	config := uc.currentConfig()
+	if config.LogScanWindow <= 0 || len(config.LogScanRules) == 0 {
+		return nil
+	}
+
+	klog.Infof("Scanning logs of restarted pods for %s (%d rules)", config.LogScanWindow, len(config.LogScanRules))
+
+	deadline := time.Now().Add(config.LogScanWindow)
+	seen := make(map[string]bool)
+	lastRead := make(map[string]metav1.Time)
+	var findings []LogFinding
+
+	ticker := time.NewTicker(logScanPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Hold off a reload while the selectors are in use
+		uc.configMu.RLock()
+		pods, err := uc.listTargetPods(ctx)
+		uc.configMu.RUnlock()
+		if err != nil {
+			klog.Warningf("Log scan failed to list pods: %v", err)
+		}
+
+		for _, pod := range pods {
+			if pod.Status.StartTime == nil || pod.Status.StartTime.Time.Before(restartedAt) {
+				continue
+			}
+
+			for _, container := range pod.Spec.Containers {
+				streamKey := fmt.Sprintf("%s/%s", pod.UID, container.Name)
+				since, ok := lastRead[streamKey]
+				if !ok {
+					since = metav1.NewTime(restartedAt)
+				}
+				readAt := metav1.Now()
+
+				matches, err := uc.scanPodLogs(ctx, pod, container.Name, since, config.LogScanRules)
+				if err != nil {
+					klog.V(2).Infof("Log scan of %s/%s (%s) failed: %v", pod.Namespace, pod.Name, container.Name, err)
+					continue
+				}
+				lastRead[streamKey] = readAt
+
+				for _, finding := range matches {
+					key := fmt.Sprintf("%s/%s/%s", streamKey, finding.Rule, finding.Line)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					klog.Warningf("Log scan: %s", finding)
+					findings = append(findings, finding)
+				}
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return findings
+		case <-ticker.C:
+		}
+	}
+
+	klog.Infof("Log scan finished with %d findings", len(findings))
+	return findings
+}
+
+// scanPodLogs reads a container's logs since the given time and matches them against rules
+func (uc *UpdateController) scanPodLogs(ctx context.Context, pod *corev1.Pod, container string, since metav1.Time, rules []LogScanRule) ([]LogFinding, error) {
+	req := uc.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		SinceTime: &since,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log stream: %w", err)
+	}
+	defer stream.Close()
+
+	var findings []LogFinding
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, rule := range rules {
+			if rule.Pattern.MatchString(line) {
+				findings = append(findings, LogFinding{
+					Namespace: pod.Namespace,
+					Pod:       pod.Name,
+					Container: container,
+					Severity:  rule.Severity,
+					Rule:      rule.Pattern.String(),
+					Line:      line,
+				})
+			}
+		}
+	}
+
+	return findings, scanner.Err()
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// logWatchResyncInterval is how often the set of followed pods is refreshed
+const logWatchResyncInterval = 30 * time.Second
+
+// outOfDateMarkers are printed by srcds as soon as Valve ships a new build
+var outOfDateMarkers = []string{
+	"Your server is out of date",
+	"MasterRequestRestart",
+}
+
+// logWatcher follows game server logs and requests an update check when a
+// server reports that it is out of date
+type logWatcher struct {
+	uc      *UpdateController
+	trigger chan struct{}
+
+	mu        sync.Mutex
+	following map[string]bool
+	// lastRead is the timestamp of the last log line read from each followed
+	// container, so a reconnect resumes where the previous stream ended
+	lastRead map[string]time.Time
+
+	// triggeredBuild is the installed build the last trigger was for
+	triggeredBuild string
+	lastTrigger    time.Time
+}
+
+func newLogWatcher(uc *UpdateController) *logWatcher {
+	return &logWatcher{
+		uc:        uc,
+		trigger:   make(chan struct{}, 1),
+		following: make(map[string]bool),
+		lastRead:  make(map[string]time.Time),
+	}
+}
+
+// run keeps a log follower attached to every running target pod until ctx is done
+func (w *logWatcher) run(ctx context.Context) {
+	klog.Info("Watching game server logs for out-of-date markers")
+
+	ticker := time.NewTicker(logWatchResyncInterval)
+	defer ticker.Stop()
+
+	for {
+		w.resync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resync starts followers for running pods that are not being followed yet
+func (w *logWatcher) resync(ctx context.Context) {
+	// Hold off a reload while the selectors are in use
+	w.uc.configMu.RLock()
+	pods, err := w.uc.listTargetPods(ctx)
+	w.uc.configMu.RUnlock()
+	if err != nil {
+		klog.Warningf("Log watcher failed to list pods: %v", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	running := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		for _, container := range pod.Spec.Containers {
+			key := fmt.Sprintf("%s/%s", pod.UID, container.Name)
+			running[key] = true
+			if w.following[key] {
+				continue
+			}
+			w.following[key] = true
+			go w.follow(ctx, key, pod, container.Name)
+		}
+	}
+
+	// Forget where containers of pods that are gone left off
+	for key := range w.lastRead {
+		if !running[key] && !w.following[key] {
+			delete(w.lastRead, key)
+		}
+	}
+}
+
+// follow streams a container's log until it ends, signalling on out-of-date markers
+func (w *logWatcher) follow(ctx context.Context, key string, pod *corev1.Pod, container string) {
+	defer func() {
+		w.mu.Lock()
+		delete(w.following, key)
+		w.mu.Unlock()
+	}()
+
+	// A container followed before resumes after its last line so markers
+	// logged between streams are not lost; a new one only shows new output
+	w.mu.Lock()
+	last, resumed := w.lastRead[key]
+	if !resumed {
+		last = time.Now()
+		w.lastRead[key] = last
+	}
+	w.mu.Unlock()
+
+	// SinceTime has second precision, so lines up to last are skipped below
+	since := metav1.NewTime(last)
+	req := w.uc.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		SinceTime:  &since,
+		Timestamps: true,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		klog.V(2).Infof("Failed to follow logs of %s/%s (%s): %v", pod.Namespace, pod.Name, container, err)
+		return
+	}
+	defer stream.Close()
+
+	klog.V(2).Infof("Following logs of %s/%s (%s)", pod.Namespace, pod.Name, container)
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		at, line, ok := splitLogTimestamp(scanner.Text())
+		if ok {
+			if !at.After(last) {
+				continue
+			}
+			last = at
+			w.mu.Lock()
+			w.lastRead[key] = at
+			w.mu.Unlock()
+		}
+
+		for _, marker := range outOfDateMarkers {
+			if strings.Contains(line, marker) {
+				w.signal(pod, line)
+				break
+			}
+		}
+	}
+}
+
+// splitLogTimestamp splits the RFC3339 timestamp the API server prefixes to
+// each line when timestamps are requested from the line itself
+func splitLogTimestamp(raw string) (time.Time, string, bool) {
+	prefix, line, found := strings.Cut(raw, " ")
+	if !found {
+		return time.Time{}, raw, false
+	}
+	at, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, raw, false
+	}
+	return at, line, true
+}
+
+// signal requests an update check for a server reporting it is out of date.
+// Markers are ignored while an update runs and from pods still running a
+// build older than the install, which the update restarts itself. Otherwise
+// each installed build gets at most one check per LOG_TRIGGER_COOLDOWN, so a
+// patch Steam had not published yet at the first check is still picked up
+// by a later marker; a failed check can be retried right away
+func (w *logWatcher) signal(pod *corev1.Pod, line string) {
+	status := w.uc.Status()
+
+	w.mu.Lock()
+	switch {
+	case status.Phase != PhaseIdle:
+		w.mu.Unlock()
+		klog.V(2).Infof("Pod %s/%s reported out of date during an update", pod.Namespace, pod.Name)
+		return
+	case !status.InstalledAt.IsZero() && podStartedAt(pod).Before(status.InstalledAt):
+		w.mu.Unlock()
+		klog.V(2).Infof("Pod %s/%s reported out of date and predates build %s, waiting for its restart", pod.Namespace, pod.Name, status.InstalledBuild)
+		return
+	case !w.lastTrigger.IsZero() && status.InstalledBuild == w.triggeredBuild &&
+		time.Since(w.lastTrigger) < w.uc.currentConfig().LogTriggerCooldown && !w.checkFailed(status):
+		w.mu.Unlock()
+		klog.V(2).Infof("Pod %s/%s reported out of date, check for build %q already triggered at %s", pod.Namespace, pod.Name, status.InstalledBuild, w.lastTrigger.Format(time.RFC3339))
+		return
+	}
+	w.triggeredBuild = status.InstalledBuild
+	w.lastTrigger = time.Now()
+	w.mu.Unlock()
+
+	klog.Infof("Pod %s/%s reported it is out of date (%q), triggering update check", pod.Namespace, pod.Name, strings.TrimSpace(line))
+
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// checkFailed reports whether the check run since the last trigger failed, so
+// the patch has not been looked up yet
+func (w *logWatcher) checkFailed(status Status) bool {
+	return status.LastCheck.After(w.lastTrigger) && status.LastCheckError != ""
+}
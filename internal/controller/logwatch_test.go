@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplitLogTimestamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantAt   time.Time
+		wantLine string
+		wantOK   bool
+	}{
+		{
+			name:     "timestamped",
+			raw:      "2024-05-01T12:00:00.123456789Z Your server is out of date",
+			wantAt:   time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC),
+			wantLine: "Your server is out of date",
+			wantOK:   true,
+		},
+		{
+			name:     "empty line",
+			raw:      "2024-05-01T12:00:00Z ",
+			wantAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			wantLine: "",
+			wantOK:   true,
+		},
+		{
+			name:     "no timestamp",
+			raw:      "Your server is out of date",
+			wantLine: "Your server is out of date",
+		},
+		{
+			name:     "no separator",
+			raw:      "MasterRequestRestart",
+			wantLine: "MasterRequestRestart",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, line, ok := splitLogTimestamp(tt.raw)
+			if ok != tt.wantOK || line != tt.wantLine || !at.Equal(tt.wantAt) {
+				t.Errorf("splitLogTimestamp() = %v, %q, %v, want %v, %q, %v", at, line, ok, tt.wantAt, tt.wantLine, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLogWatcherSignal(t *testing.T) {
+	installedAt := time.Now().Add(-time.Hour)
+	cooldown := 10 * time.Minute
+
+	tests := []struct {
+		name string
+		// status is the controller status when the marker arrives
+		status Status
+		// triggeredBuild and triggeredAgo describe the previous trigger, none when triggeredAgo is 0
+		triggeredBuild string
+		triggeredAgo   time.Duration
+		podStartedAt   time.Time
+		want           bool
+	}{
+		{
+			name:         "first marker",
+			status:       Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt},
+			podStartedAt: installedAt.Add(time.Minute),
+			want:         true,
+		},
+		{
+			name:         "update running",
+			status:       Status{Phase: PhaseDownloading, InstalledBuild: "100", InstalledAt: installedAt},
+			podStartedAt: installedAt.Add(time.Minute),
+		},
+		{
+			name:         "pod predates install",
+			status:       Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt},
+			podStartedAt: installedAt.Add(-time.Minute),
+		},
+		{
+			name:           "same build within cooldown",
+			status:         Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt},
+			triggeredBuild: "100",
+			triggeredAgo:   time.Minute,
+			podStartedAt:   installedAt.Add(time.Minute),
+		},
+		{
+			name:           "same build after cooldown",
+			status:         Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt},
+			triggeredBuild: "100",
+			triggeredAgo:   cooldown + time.Minute,
+			podStartedAt:   installedAt.Add(time.Minute),
+			want:           true,
+		},
+		{
+			name:           "new build within cooldown",
+			status:         Status{Phase: PhaseIdle, InstalledBuild: "200", InstalledAt: installedAt},
+			triggeredBuild: "100",
+			triggeredAgo:   time.Minute,
+			podStartedAt:   installedAt.Add(time.Minute),
+			want:           true,
+		},
+		{
+			name:           "triggered check failed",
+			status:         Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt, LastCheck: time.Now(), LastCheckError: "steamcmd timed out"},
+			triggeredBuild: "100",
+			triggeredAgo:   time.Minute,
+			podStartedAt:   installedAt.Add(time.Minute),
+			want:           true,
+		},
+		{
+			name:           "check before trigger failed",
+			status:         Status{Phase: PhaseIdle, InstalledBuild: "100", InstalledAt: installedAt, LastCheck: time.Now().Add(-2 * time.Minute), LastCheckError: "steamcmd timed out"},
+			triggeredBuild: "100",
+			triggeredAgo:   time.Minute,
+			podStartedAt:   installedAt.Add(time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{config: &Config{LogTriggerCooldown: cooldown}, status: tt.status}
+			w := newLogWatcher(uc)
+			if tt.triggeredAgo > 0 {
+				w.triggeredBuild = tt.triggeredBuild
+				w.lastTrigger = time.Now().Add(-tt.triggeredAgo)
+			}
+
+			w.signal(testPod(tt.podStartedAt, tt.podStartedAt), "Your server is out of date")
+
+			select {
+			case <-w.trigger:
+				if !tt.want {
+					t.Error("signal() triggered a check, want none")
+				}
+			default:
+				if tt.want {
+					t.Error("signal() did not trigger a check")
+				}
+			}
+		})
+	}
+}
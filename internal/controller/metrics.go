@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricLabels returns the labels identifying this controller's install
+func (uc *UpdateController) metricLabels(extra ...string) prometheus.Labels {
+	labels := prometheus.Labels{"app_id": uc.config.SteamAppID, "policy": uc.config.PolicyName}
+	for i := 0; i+1 < len(extra); i += 2 {
+		labels[extra[i]] = extra[i+1]
+	}
+	return labels
+}
+
+// recordCheckMetrics publishes the outcome of an update check
+func (uc *UpdateController) recordCheckMetrics(err error) {
+	metrics.Checks.With(uc.metricLabels("result", metrics.Result(err == nil))).Inc()
+	if err != nil {
+		metrics.Failures.With(uc.metricLabels("class", "check")).Inc()
+		return
+	}
+
+	metrics.SetTimestamp(metrics.LastCheckTimestamp.With(uc.metricLabels()), time.Now())
+	metrics.InstalledBuild.With(uc.metricLabels()).Set(metrics.BuildValue(uc.state.InstalledBuild))
+	if latest := uc.steamClient.LatestBuild(); latest != "" {
+		metrics.LatestBuild.With(uc.metricLabels()).Set(metrics.BuildValue(latest))
+	}
+}
+
+// recordUpdateMetrics publishes the outcome of an update; failureClass names the stage that failed
+func (uc *UpdateController) recordUpdateMetrics(result *UpdateResult, failureClass string) {
+	metrics.Updates.With(uc.metricLabels("result", metrics.Result(result.Success))).Inc()
+	if !result.Success {
+		metrics.Failures.With(uc.metricLabels("class", failureClass)).Inc()
+	} else {
+		metrics.SetTimestamp(metrics.LastUpdateTimestamp.With(uc.metricLabels()), result.FinishedAt)
+	}
+	metrics.InstalledBuild.With(uc.metricLabels()).Set(metrics.BuildValue(uc.state.InstalledBuild))
+
+	if result.Restart == nil {
+		return
+	}
+	outcomes := map[string][]WorkloadOutcome{
+		"succeeded": result.Restart.Succeeded,
+		"failed":    result.Restart.Failed,
+		"skipped":   result.Restart.Skipped,
+	}
+	for outcome, workloads := range outcomes {
+		for _, w := range workloads {
+			metrics.WorkloadRestarts.With(uc.metricLabels("namespace", w.Namespace, "kind", w.Kind, "name", w.Name, "outcome", outcome)).Inc()
+		}
+	}
+}
+
+// observeStage records the duration of a steamcmd stage
+func (uc *UpdateController) observeStage(stage string, duration time.Duration, err error) {
+	metrics.StageDuration.With(uc.metricLabels("stage", stage, "result", metrics.Result(err == nil))).Observe(duration.Seconds())
+}
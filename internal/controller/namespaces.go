@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/UDL-TF/RestartController/pkg/k8s"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// requiredPermission is an API access the controller needs in each target namespace
+type requiredPermission struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+}
+
+func (p requiredPermission) String() string {
+	resource := p.resource
+	if p.subresource != "" {
+		resource += "/" + p.subresource
+	}
+	if p.group != "" {
+		resource += "." + p.group
+	}
+	return p.verb + " " + resource
+}
+
+// requiredPermissions covers listing targets and the built-in restart paths
+var requiredPermissions = []requiredPermission{
+	{verb: "list", resource: "pods"},
+	{verb: "get", resource: "pods", subresource: "log"},
+	{verb: "delete", resource: "pods"},
+	{verb: "create", resource: "pods", subresource: "eviction"},
+	{verb: "update", group: "apps", resource: "deployments"},
+	{verb: "update", group: "apps", resource: "statefulsets"},
+	{verb: "update", group: "apps", resource: "daemonsets"},
+	{verb: "patch", group: "apps", resource: "deployments", subresource: "scale"},
+}
+
+// targetNamespaces returns the configured namespaces plus any matching the namespace selector
+func (uc *UpdateController) targetNamespaces(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, ns := range uc.config.Namespaces {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	if uc.config.NamespaceSelector != "" {
+		list, err := uc.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: uc.config.NamespaceSelector})
+		if err != nil {
+			return namespaces, fmt.Errorf("failed to list namespaces matching %q: %w", uc.config.NamespaceSelector, err)
+		}
+		for _, ns := range list.Items {
+			if !seen[ns.Name] {
+				seen[ns.Name] = true
+				namespaces = append(namespaces, ns.Name)
+			}
+		}
+	}
+
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// podSelectorFor returns the pod selector for a namespace, honouring per-namespace overrides
+func (uc *UpdateController) podSelectorFor(namespace string) string {
+	if selector, ok := uc.config.NamespacePodSelectors[namespace]; ok {
+		return selector
+	}
+	return uc.config.PodSelector
+}
+
+// k8sClientFor returns the namespaced RestartController client for a namespace
+func (uc *UpdateController) k8sClientFor(namespace string) *k8s.Client {
+	uc.k8sClientsMu.Lock()
+	defer uc.k8sClientsMu.Unlock()
+
+	client, ok := uc.k8sClients[namespace]
+	if !ok {
+		client = k8s.NewClient(uc.clientset, namespace)
+		uc.k8sClients[namespace] = client
+	}
+	return client
+}
+
+// checkPermissions reports, for every target namespace, any required access the controller lacks
+func (uc *UpdateController) checkPermissions(ctx context.Context) {
+	namespaces, err := uc.targetNamespaces(ctx)
+	if err != nil {
+		klog.Warningf("Permission check: %v", err)
+	}
+
+	klog.Infof("Target namespaces: %v", namespaces)
+
+	missing := 0
+	for _, ns := range namespaces {
+		for _, perm := range requiredPermissions {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   ns,
+						Verb:        perm.verb,
+						Group:       perm.group,
+						Resource:    perm.resource,
+						Subresource: perm.subresource,
+					},
+				},
+			}
+
+			resp, err := uc.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				klog.Warningf("Permission check for %s in %s failed: %v", perm, ns, err)
+				continue
+			}
+
+			if !resp.Status.Allowed {
+				missing++
+				klog.Warningf("Missing permission in namespace %s: %s", ns, perm)
+			}
+		}
+	}
+
+	if missing == 0 {
+		klog.Infof("Permission check passed for %d namespaces", len(namespaces))
+	}
+}
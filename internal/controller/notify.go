@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// NotificationSinkWebhook POSTs a JSON summary of each update to a URL
+const NotificationSinkWebhook = "webhook"
+
+// notifyTimeout bounds a single notification delivery
+const notifyTimeout = 10 * time.Second
+
+// NotificationSink is a destination for update results
+type NotificationSink struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+func (s NotificationSink) validate() error {
+	if s.Type != NotificationSinkWebhook {
+		return fmt.Errorf("unknown notification sink type %q", s.Type)
+	}
+	if s.URL == "" {
+		return fmt.Errorf("%s notification sink has no url", s.Type)
+	}
+	return nil
+}
+
+// updateNotification is the JSON body sent to webhook sinks
+type updateNotification struct {
+	Policy     string    `json:"policy,omitempty"`
+	AppID      string    `json:"appId"`
+	Build      string    `json:"build,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Restarted  []string  `json:"restarted,omitempty"`
+	Failed     []string  `json:"failed,omitempty"`
+	Skipped    []string  `json:"skipped,omitempty"`
+
+	// LogFindings are the post-restart log scan matches, warnings included
+	LogFindings  []LogFinding `json:"logFindings,omitempty"`
+	RolledBackTo string       `json:"rolledBackTo,omitempty"`
+}
+
+// notify delivers an update result to every configured sink; failures are only logged
+func (uc *UpdateController) notify(ctx context.Context, result *UpdateResult) {
+	if len(uc.config.Notifications) == 0 {
+		return
+	}
+
+	notification := updateNotification{
+		Policy:     uc.config.PolicyName,
+		AppID:      uc.config.SteamAppID,
+		Build:      uc.state.InstalledBuild,
+		Success:    result.Success,
+		Error:      result.Error,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+
+		LogFindings:  result.LogFindings,
+		RolledBackTo: result.RolledBackTo,
+	}
+	if result.Restart != nil {
+		notification.Restarted = outcomeStrings(result.Restart.Succeeded)
+		notification.Failed = outcomeStrings(result.Restart.Failed)
+		notification.Skipped = outcomeStrings(result.Restart.Skipped)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		klog.Warningf("Failed to encode update notification: %v", err)
+		return
+	}
+
+	for _, sink := range uc.config.Notifications {
+		if err := postWebhook(ctx, sink.URL, body); err != nil {
+			klog.Warningf("Failed to notify %s sink: %v", sink.Type, err)
+		}
+	}
+}
+
+func postWebhook(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func outcomeStrings(outcomes []WorkloadOutcome) []string {
+	values := make([]string, len(outcomes))
+	for i, outcome := range outcomes {
+		values[i] = outcome.String()
+	}
+	return values
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrNoTargets is returned when a restart finds no target pods
+var ErrNoTargets = errors.New("no target pods found")
+
+// CheckResult is the outcome of a one-shot update check
+type CheckResult struct {
+	App             string `json:"app"`
+	AppID           string `json:"appId"`
+	Branch          string `json:"branch"`
+	InstalledBuild  string `json:"installedBuild"`
+	LatestBuild     string `json:"latestBuild"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+}
+
+// openOnce prepares the controller for a single command outside the main
+// loop; repair runs the same startup repair and resume as Run, without waiting
+// for pause or maintenance windows. The returned function flushes Events and
+// must be called when the command is done
+func (uc *UpdateController) openOnce(ctx context.Context, repair bool) func() {
+	broadcaster := newEventBroadcaster(uc.clientset)
+	uc.recorder = newEventRecorder(broadcaster)
+	uc.eventTarget = controllerPodReference(ctx, uc.clientset)
+
+	if repair {
+		uc.start(ctx, false)
+	} else {
+		uc.loadState()
+		uc.seedInstall()
+	}
+
+	return broadcaster.Shutdown
+}
+
+// Check compares the installed build with the latest build without changing anything
+func (uc *UpdateController) Check(ctx context.Context) (*CheckResult, error) {
+	defer uc.openOnce(ctx, false)()
+
+	available, err := uc.steamClient.CheckUpdate(ctx)
+	uc.recordCheckMetrics(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+
+	installed, _, err := uc.steamClient.InstalledBuild()
+	if err != nil {
+		klog.V(2).Infof("Failed to read installed build: %v", err)
+	}
+
+	return &CheckResult{
+		App:             uc.config.SteamApp,
+		AppID:           uc.config.SteamAppID,
+		Branch:          uc.config.SteamBranch,
+		InstalledBuild:  installed,
+		LatestBuild:     uc.steamClient.LatestBuild(),
+		UpdateAvailable: available,
+	}, nil
+}
+
+// Update installs the latest build and restarts targets when an update is
+// available, or unconditionally when force is set; it returns a nil result
+// when there was nothing to do. Pause and maintenance windows only apply to
+// the main loop and are ignored
+func (uc *UpdateController) Update(ctx context.Context, force bool) (*UpdateResult, error) {
+	defer uc.openOnce(ctx, true)()
+
+	available, err := uc.steamClient.CheckUpdate(ctx)
+	uc.recordCheckMetrics(err)
+	if err != nil && !force {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	if !available && !force {
+		klog.Info("No updates available")
+		return nil, nil
+	}
+
+	err = uc.applyUpdate(ctx)
+	return uc.lastResult, err
+}
+
+// Validate verifies the installed files without restarting anything
+func (uc *UpdateController) Validate(ctx context.Context) error {
+	defer uc.openOnce(ctx, false)()
+	return uc.validate(ctx)
+}
+
+// RestartTargets restarts every target workload, or only those still running
+// an older build when outdatedOnly is set, applying the partial restart policy
+func (uc *UpdateController) RestartTargets(ctx context.Context, outdatedOnly bool) (*RestartResult, error) {
+	defer uc.openOnce(ctx, false)()
+
+	if outdatedOnly {
+		workloads, excluded, err := uc.collectOutdatedWorkloads(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result := uc.restartWorkloads(ctx, workloads)
+		result.Excluded = excluded
+		return result, result.err(uc.config.PartialRestartPolicy)
+	}
+
+	return uc.restartAllTargets(ctx)
+}
+
+// Snapshot returns the persisted state and current targets without contacting Steam
+func (uc *UpdateController) Snapshot(ctx context.Context) Status {
+	defer uc.openOnce(ctx, false)()
+
+	uc.refreshTargets(ctx)
+	uc.publishStatus()
+	return uc.Status()
+}
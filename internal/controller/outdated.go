@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// recordInstall remembers the build now on disk and when it was installed
+func (uc *UpdateController) recordInstall(installedAt time.Time) {
+	buildID, _, err := uc.steamClient.InstalledBuild()
+	if err != nil {
+		klog.Warningf("Failed to read installed build: %v", err)
+	}
+
+	uc.state.InstalledBuild = buildID
+	uc.state.InstalledAt = installedAt
+	uc.state.InstallSeeded = false
+	klog.Infof("Installed build %s at %s", buildID, installedAt.Format(time.RFC3339))
+}
+
+// seedInstall initialises the install record from the manifest when the
+// controller starts without a persisted record of the build on disk, so pods
+// predating the current build are still reported. The manifest's LastUpdated
+// also moves on out-of-band file changes, so these pods are left running
+func (uc *UpdateController) seedInstall() {
+	buildID, updatedAt, err := uc.steamClient.InstalledBuild()
+	if err != nil || buildID == "" || updatedAt.IsZero() {
+		return
+	}
+
+	if !uc.state.InstalledAt.IsZero() && uc.state.InstalledBuild == buildID {
+		return
+	}
+
+	uc.state.InstalledBuild = buildID
+	uc.state.InstalledAt = updatedAt
+	uc.state.InstallSeeded = true
+	klog.Infof("Build %s on disk was installed at %s, outside this controller; older pods are reported but not restarted", buildID, updatedAt.Format(time.RFC3339))
+}
+
+// isPodOutdated reports whether a pod started before the current build was installed
+func (uc *UpdateController) isPodOutdated(pod *corev1.Pod) bool {
+	if uc.state.InstalledAt.IsZero() {
+		return true
+	}
+
+	startedAt := podStartedAt(pod)
+	return startedAt.IsZero() || startedAt.Before(uc.state.InstalledAt)
+}
+
+// podStartedAt returns when the earliest running container of a pod started,
+// falling back to the pod's start time
+func podStartedAt(pod *corev1.Pod) time.Time {
+	var earliest time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running == nil {
+			continue
+		}
+		startedAt := status.State.Running.StartedAt.Time
+		if earliest.IsZero() || startedAt.Before(earliest) {
+			earliest = startedAt
+		}
+	}
+
+	if earliest.IsZero() && pod.Status.StartTime != nil {
+		earliest = pod.Status.StartTime.Time
+	}
+	return earliest
+}
+
+// collectOutdatedWorkloads groups target pods still running an older build by
+// their top-level controller, and returns the pods excluded from targeting
+func (uc *UpdateController) collectOutdatedWorkloads(ctx context.Context) ([]*workload, []ExcludedPod, error) {
+	selection, err := uc.selectTargetPods(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	pods := selection.Pods
+
+	outdated := make([]*corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if uc.isPodOutdated(pod) {
+			outdated = append(outdated, pod)
+		} else {
+			klog.V(2).Infof("Pod %s/%s started after the last install, not restarting", pod.Namespace, pod.Name)
+		}
+	}
+
+	klog.Infof("Found %d target pods, %d running an outdated build", len(pods), len(outdated))
+	metrics.OutdatedPods.With(uc.metricLabels()).Set(float64(len(outdated)))
+	return uc.groupWorkloads(ctx, outdated), selection.Excluded, nil
+}
+
+// reconcileOutdated restarts workloads whose pods survived an earlier
+// restart and are still running a build older than the one installed
+func (uc *UpdateController) reconcileOutdated(ctx context.Context) {
+	if uc.state.InstalledAt.IsZero() {
+		return
+	}
+
+	workloads, _, err := uc.collectOutdatedWorkloads(ctx)
+	if err != nil {
+		klog.Warningf("Failed to reconcile outdated pods: %v", err)
+		return
+	}
+
+	// Only an install the controller ran itself is worth restarting pods for
+	if uc.state.InstallSeeded {
+		if len(workloads) > 0 && !uc.seedReported {
+			uc.seedReported = true
+			for _, w := range workloads {
+				klog.Infof("%s has %d pods started before build %s was found on disk, leaving them running", w, len(w.Pods), uc.state.InstalledBuild)
+			}
+		}
+		return
+	}
+
+	pending := workloads[:0]
+	for _, w := range workloads {
+		// Workloads still rolling out will replace their old pods on their own
+		if w.Object != nil && !workloadReady(w.Object) {
+			klog.V(2).Infof("%s is still rolling out, not restarting it again", w)
+			continue
+		}
+		// Manual and skipped workloads were reported by the update's restart
+		if policy, _ := uc.restartPolicy(w); policy != WorkloadPolicyAuto {
+			klog.V(2).Infof("%s has restart policy %s, not restarting it", w, policy)
+			continue
+		}
+		pending = append(pending, w)
+	}
+
+	if len(pending) == 0 {
+		klog.V(2).Info("All target pods are running the installed build")
+		return
+	}
+
+	klog.Infof("Found %d workloads still running a build older than %s, restarting", len(pending), uc.state.InstalledBuild)
+	result := uc.restartWorkloads(ctx, pending)
+	if err := result.err(uc.config.PartialRestartPolicy); err != nil {
+		klog.Errorf("Failed to restart outdated workloads: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/steamcmd"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testPod returns a running pod scheduled at startTime whose containers started at the given times;
+// a zero time leaves that container waiting
+func testPod(startTime time.Time, containers ...time.Time) *corev1.Pod {
+	pod := &corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	if !startTime.IsZero() {
+		pod.Status.StartTime = &metav1.Time{Time: startTime}
+	}
+	for _, startedAt := range containers {
+		status := corev1.ContainerStatus{}
+		if startedAt.IsZero() {
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}
+		} else {
+			status.State.Running = &corev1.ContainerStateRunning{StartedAt: metav1.Time{Time: startedAt}}
+		}
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, status)
+	}
+	return pod
+}
+
+func TestPodStartedAt(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want time.Time
+	}{
+		{"no status", testPod(time.Time{}), time.Time{}},
+		{"pod start time only", testPod(base), base},
+		{"container start", testPod(base, base.Add(time.Minute)), base.Add(time.Minute)},
+		{"earliest running container", testPod(base, base.Add(2*time.Minute), base.Add(time.Minute)), base.Add(time.Minute)},
+		{"waiting containers skipped", testPod(base, time.Time{}, base.Add(time.Minute)), base.Add(time.Minute)},
+		{"no running container falls back to pod", testPod(base, time.Time{}), base},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podStartedAt(tt.pod); !got.Equal(tt.want) {
+				t.Errorf("podStartedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPodOutdated(t *testing.T) {
+	installedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		installedAt time.Time
+		pod         *corev1.Pod
+		want        bool
+	}{
+		{"no install record", time.Time{}, testPod(installedAt, installedAt.Add(time.Hour)), true},
+		{"started before install", installedAt, testPod(installedAt.Add(-time.Hour), installedAt.Add(-time.Hour)), true},
+		{"started after install", installedAt, testPod(installedAt, installedAt.Add(time.Minute)), false},
+		{"started at install", installedAt, testPod(installedAt, installedAt), false},
+		{"container restarted after install", installedAt, testPod(installedAt.Add(-time.Hour), installedAt.Add(time.Minute)), false},
+		{"unknown start", installedAt, testPod(time.Time{}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{state: updateState{InstalledAt: tt.installedAt}}
+			if got := uc.isPodOutdated(tt.pod); got != tt.want {
+				t.Errorf("isPodOutdated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeedInstall(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	manifest := fmt.Sprintf("\"AppState\"\n{\n\t\"buildid\"\t\t\"200\"\n\t\"LastUpdated\"\t\t\"%d\"\n}\n", updatedAt.Unix())
+
+	tests := []struct {
+		name       string
+		state      updateState
+		wantAt     time.Time
+		wantSeeded bool
+	}{
+		{
+			name:       "no record",
+			wantAt:     updatedAt,
+			wantSeeded: true,
+		},
+		{
+			name:       "older build recorded",
+			state:      updateState{InstalledBuild: "100", InstalledAt: updatedAt.Add(-time.Hour)},
+			wantAt:     updatedAt,
+			wantSeeded: true,
+		},
+		{
+			name:   "installed by the controller",
+			state:  updateState{InstalledBuild: "200", InstalledAt: updatedAt.Add(time.Minute)},
+			wantAt: updatedAt.Add(time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			steamapps := filepath.Join(dir, "steamapps")
+			if err := os.MkdirAll(steamapps, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(steamapps, "appmanifest_232250.acf"), []byte(manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			uc := &UpdateController{
+				state:       tt.state,
+				steamClient: steamcmd.NewClient(t.TempDir(), "tf", "232250", dir, "tf_update.txt"),
+			}
+			uc.seedInstall()
+
+			if uc.state.InstalledBuild != "200" || !uc.state.InstalledAt.Equal(tt.wantAt) {
+				t.Errorf("seedInstall() recorded build %q at %v, want build 200 at %v", uc.state.InstalledBuild, uc.state.InstalledAt, tt.wantAt)
+			}
+			if uc.state.InstallSeeded != tt.wantSeeded {
+				t.Errorf("seedInstall() InstallSeeded = %v, want %v", uc.state.InstallSeeded, tt.wantSeeded)
+			}
+		})
+	}
+}
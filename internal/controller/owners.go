@@ -0,0 +1,204 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// KindAction is how a workload kind without built-in restart support is handled
+type KindAction string
+
+const (
+	// KindActionAnnotate bumps the restart annotation on spec.template.metadata
+	KindActionAnnotate KindAction = "annotate"
+	// KindActionDeletePods deletes the workload's pods and lets it replace them
+	KindActionDeletePods KindAction = "delete-pods"
+	// KindActionSkip leaves the workload alone
+	KindActionSkip KindAction = "skip"
+)
+
+// podGVR is the core Pod resource, used for pods without a controller
+var podGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+// workload is the top-level controlling object of one or more target pods
+type workload struct {
+	Namespace   string
+	APIVersion  string
+	Kind        string
+	Name        string
+	UID         types.UID
+	Resource    schema.GroupVersionResource
+	Annotations map[string]string
+	Pods        []*corev1.Pod
+
+	// Object is the workload as last read from the API, nil for bare pods or unmapped kinds
+	Object *unstructured.Unstructured
+}
+
+func (w *workload) key() string {
+	return fmt.Sprintf("%s/%s/%s", w.Namespace, w.groupKind(), w.Name)
+}
+
+func (w *workload) groupKind() schema.GroupKind {
+	gv, _ := schema.ParseGroupVersion(w.APIVersion)
+	return schema.GroupKind{Group: gv.Group, Kind: w.Kind}
+}
+
+func (w *workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// ownerResolver walks ownerReferences up to the topmost controlling object
+type ownerResolver struct {
+	dynamicClient dynamic.Interface
+	mapper        meta.ResettableRESTMapper
+
+	// cache maps an immediate controller UID to its resolved top-level owner
+	cache map[types.UID]workload
+}
+
+func newOwnerResolver(dynamicClient dynamic.Interface, mapper meta.ResettableRESTMapper) *ownerResolver {
+	return &ownerResolver{
+		dynamicClient: dynamicClient,
+		mapper:        mapper,
+		cache:         make(map[types.UID]workload),
+	}
+}
+
+// resolve returns the top-level controller of a pod
+func (r *ownerResolver) resolve(ctx context.Context, pod *corev1.Pod) (*workload, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		// Bare pods are their own workload
+		return &workload{
+			Namespace:   pod.Namespace,
+			APIVersion:  "v1",
+			Kind:        "Pod",
+			Name:        pod.Name,
+			UID:         pod.UID,
+			Resource:    podGVR,
+			Annotations: pod.Annotations,
+		}, nil
+	}
+
+	if cached, ok := r.cache[ref.UID]; ok {
+		return &cached, nil
+	}
+
+	top := workload{
+		Namespace:  pod.Namespace,
+		APIVersion: ref.APIVersion,
+		Kind:       ref.Kind,
+		Name:       ref.Name,
+		UID:        ref.UID,
+	}
+
+	// readable is the highest owner fetched so far, the fallback when a parent cannot be read
+	var readable *workload
+	for {
+		gvr, err := r.resourceFor(ref)
+		if err != nil {
+			// Without a mapping the current owner is as high as we can see
+			klog.V(2).Infof("Cannot map %s %s, treating it as top-level: %v", ref.Kind, ref.Name, err)
+			break
+		}
+		top.Resource = gvr
+
+		obj, err := r.dynamicClient.Resource(gvr).Namespace(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			// Commonly Forbidden on custom owners the ClusterRole does not cover
+			if readable != nil {
+				klog.Warningf("Cannot get %s %s, treating %s %s as top-level: %v", ref.Kind, ref.Name, readable.Kind, readable.Name, err)
+				top = *readable
+			} else {
+				klog.Warningf("Cannot get %s %s, treating it as top-level: %v", ref.Kind, ref.Name, err)
+			}
+			break
+		}
+		top.Annotations = obj.GetAnnotations()
+		top.Object = obj
+		current := top
+		readable = &current
+
+		parent := metav1.GetControllerOf(obj)
+		if parent == nil {
+			break
+		}
+
+		ref = parent
+		top = workload{
+			Namespace:  pod.Namespace,
+			APIVersion: ref.APIVersion,
+			Kind:       ref.Kind,
+			Name:       ref.Name,
+			UID:        ref.UID,
+		}
+	}
+
+	r.cache[metav1.GetControllerOf(pod).UID] = top
+	return &top, nil
+}
+
+// resourceFor maps an owner reference to its API resource, refreshing discovery once on a miss
+func (r *ownerResolver) resourceFor(ref *metav1.OwnerReference) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid apiVersion %q: %w", ref.APIVersion, err)
+	}
+
+	gk := schema.GroupKind{Group: gv.Group, Kind: ref.Kind}
+	mapping, err := r.mapper.RESTMapping(gk, gv.Version)
+	if meta.IsNoMatchError(err) {
+		r.mapper.Reset()
+		mapping, err = r.mapper.RESTMapping(gk, gv.Version)
+	}
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+
+	return mapping.Resource, nil
+}
+
+// groupWorkloads groups pods by their top-level controller
+func (uc *UpdateController) groupWorkloads(ctx context.Context, pods []*corev1.Pod) []*workload {
+	resolver := newOwnerResolver(uc.dynamicClient, uc.restMapper)
+	byKey := make(map[string]*workload)
+	var workloads []*workload
+
+	for _, pod := range pods {
+		w, err := resolver.resolve(ctx, pod)
+		if err != nil {
+			klog.Warningf("Failed to get owner for pod %s: %v", pod.Name, err)
+			continue
+		}
+
+		if existing, ok := byKey[w.key()]; ok {
+			existing.Pods = append(existing.Pods, pod)
+			continue
+		}
+
+		w.Pods = []*corev1.Pod{pod}
+		byKey[w.key()] = w
+		workloads = append(workloads, w)
+	}
+
+	return workloads
+}
+
+// kindAction returns the configured action for a kind without built-in support
+func (uc *UpdateController) kindAction(w *workload) (KindAction, bool) {
+	if action, ok := uc.config.UnknownKindActions[w.groupKind().String()]; ok {
+		return action, true
+	}
+	action, ok := uc.config.UnknownKindActions[w.Kind]
+	return action, ok
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+// workloadReadyPollInterval is how often a workload is polled while honouring max-wait
+const workloadReadyPollInterval = 5 * time.Second
+
+// WorkloadPolicy controls whether a workload is restarted after an update
+type WorkloadPolicy string
+
+const (
+	// WorkloadPolicyAuto restarts the workload automatically
+	WorkloadPolicyAuto WorkloadPolicy = "auto"
+	// WorkloadPolicyManual leaves the restart to an operator
+	WorkloadPolicyManual WorkloadPolicy = "manual"
+	// WorkloadPolicySkip ignores the workload entirely
+	WorkloadPolicySkip WorkloadPolicy = "skip"
+)
+
+// WorkloadPlan is the effective restart plan for a single workload
+type WorkloadPlan struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Pods      int             `json:"pods"`
+	Policy    WorkloadPolicy  `json:"policy"`
+	Strategy  RestartStrategy `json:"strategy"`
+	Priority  int             `json:"priority"`
+	MaxWait   time.Duration   `json:"maxWait,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+
+	workload *workload
+}
+
+func (p *WorkloadPlan) String() string {
+	s := fmt.Sprintf("%s %s/%s: policy=%s strategy=%s priority=%d pods=%d", p.Kind, p.Namespace, p.Name, p.Policy, p.Strategy, p.Priority, p.Pods)
+	if p.MaxWait > 0 {
+		s += fmt.Sprintf(" max-wait=%s", p.MaxWait)
+	}
+	if p.Reason != "" {
+		s += fmt.Sprintf(" (%s)", p.Reason)
+	}
+	return s
+}
+
+// planRestarts builds the effective plan for each workload from its
+// annotations, ordered by descending priority
+func (uc *UpdateController) planRestarts(workloads []*workload) []*WorkloadPlan {
+	plans := make([]*WorkloadPlan, 0, len(workloads))
+
+	for _, w := range workloads {
+		plan := &WorkloadPlan{
+			Namespace: w.Namespace,
+			Kind:      w.Kind,
+			Name:      w.Name,
+			Pods:      len(w.Pods),
+			Strategy:  uc.restartStrategy(w),
+			MaxWait:   uc.config.RestartMaxWait,
+			workload:  w,
+		}
+
+		plan.Policy, plan.Reason = uc.restartPolicy(w)
+
+		if value, ok := w.Annotations[priorityAnnotation]; ok {
+			if priority, err := strconv.Atoi(value); err == nil {
+				plan.Priority = priority
+			} else {
+				klog.Warningf("Ignoring invalid %s annotation %q on %s", priorityAnnotation, value, w)
+			}
+		}
+
+		if value, ok := w.Annotations[maxWaitAnnotation]; ok {
+			if maxWait, err := time.ParseDuration(value); err == nil {
+				plan.MaxWait = maxWait
+			} else {
+				klog.Warningf("Ignoring invalid %s annotation %q on %s", maxWaitAnnotation, value, w)
+			}
+		}
+
+		plans = append(plans, plan)
+	}
+
+	sort.SliceStable(plans, func(i, j int) bool {
+		return plans[i].Priority > plans[j].Priority
+	})
+
+	return plans
+}
+
+// restartPolicy returns whether a workload is restarted automatically and
+// the setting that decided it, empty for the default
+func (uc *UpdateController) restartPolicy(w *workload) (WorkloadPolicy, string) {
+	if action, ok := uc.kindAction(w); ok && action == KindActionSkip {
+		return WorkloadPolicySkip, "UNKNOWN_KIND_ACTIONS skip"
+	}
+
+	if value, ok := w.Annotations[policyAnnotation]; ok {
+		switch policy := WorkloadPolicy(value); policy {
+		case WorkloadPolicyAuto, WorkloadPolicyManual, WorkloadPolicySkip:
+			return policy, fmt.Sprintf("%s annotation", policyAnnotation)
+		default:
+			klog.Warningf("Ignoring invalid %s annotation %q on %s", policyAnnotation, value, w)
+		}
+	}
+	return WorkloadPolicyAuto, ""
+}
+
+// waitForWorkloadReady waits up to maxWait for a restarted workload to report
+// all replicas updated and ready. Timing out only ends the wait
+func (uc *UpdateController) waitForWorkloadReady(ctx context.Context, w *workload, maxWait time.Duration) {
+	if w.Resource.Empty() || w.Kind == "Pod" {
+		return
+	}
+
+	klog.Infof("Waiting up to %s for %s to become ready", maxWait, w)
+	deadline := time.Now().Add(maxWait)
+	client := uc.dynamicClient.Resource(w.Resource).Namespace(w.Namespace)
+
+	for {
+		obj, err := client.Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.Warningf("Failed to get %s while waiting for readiness: %v", w, err)
+			return
+		}
+
+		if workloadReady(obj) {
+			klog.Infof("%s is ready", w)
+			return
+		}
+
+		if time.Now().After(deadline) {
+			klog.Warningf("%s not ready after %s, moving on", w, maxWait)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(workloadReadyPollInterval):
+		}
+	}
+}
+
+// workloadReady interprets the common replica status fields of a workload
+func workloadReady(obj *unstructured.Unstructured) bool {
+	if observed, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found && observed < obj.GetGeneration() {
+		return false
+	}
+
+	// DaemonSets report scheduled counts rather than replicas
+	if desired, found, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled"); found {
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+		return updated >= desired && ready >= desired
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	if updated, found, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas"); found && updated < replicas {
+		return false
+	}
+
+	// Agones Fleets count allocated GameServers separately from ready ones
+	ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+	allocated, _, _ := unstructured.NestedInt64(obj.Object, "status", "allocatedReplicas")
+	return ready+allocated >= replicas
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testWorkload returns a Deployment named name in namespace game-servers with the given annotations as key/value pairs
+func testWorkload(name string, annotations ...string) *workload {
+	w := &workload{Namespace: "game-servers", APIVersion: "apps/v1", Kind: "Deployment", Name: name, Annotations: map[string]string{}}
+	for i := 0; i+1 < len(annotations); i += 2 {
+		w.Annotations[annotations[i]] = annotations[i+1]
+	}
+	return w
+}
+
+func TestRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		workload   *workload
+		actions    map[string]KindAction
+		want       WorkloadPolicy
+		wantReason string
+	}{
+		{
+			name:     "default",
+			workload: testWorkload("tf2"),
+			want:     WorkloadPolicyAuto,
+		},
+		{
+			name:       "manual annotation",
+			workload:   testWorkload("tf2", policyAnnotation, "manual"),
+			want:       WorkloadPolicyManual,
+			wantReason: policyAnnotation + " annotation",
+		},
+		{
+			name:       "skip annotation",
+			workload:   testWorkload("tf2", policyAnnotation, "skip"),
+			want:       WorkloadPolicySkip,
+			wantReason: policyAnnotation + " annotation",
+		},
+		{
+			name:     "invalid annotation ignored",
+			workload: testWorkload("tf2", policyAnnotation, "sometimes"),
+			want:     WorkloadPolicyAuto,
+		},
+		{
+			name:       "kind skipped",
+			workload:   testWorkload("tf2", policyAnnotation, "auto"),
+			actions:    map[string]KindAction{"Deployment": KindActionSkip},
+			want:       WorkloadPolicySkip,
+			wantReason: "UNKNOWN_KIND_ACTIONS skip",
+		},
+		{
+			name:       "kind and group skipped",
+			workload:   testWorkload("tf2"),
+			actions:    map[string]KindAction{"Deployment.apps": KindActionSkip},
+			want:       WorkloadPolicySkip,
+			wantReason: "UNKNOWN_KIND_ACTIONS skip",
+		},
+		{
+			name:       "other kind action keeps the annotation",
+			workload:   testWorkload("tf2", policyAnnotation, "manual"),
+			actions:    map[string]KindAction{"Deployment": KindActionAnnotate},
+			want:       WorkloadPolicyManual,
+			wantReason: policyAnnotation + " annotation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{config: &Config{UnknownKindActions: tt.actions}}
+			got, reason := uc.restartPolicy(tt.workload)
+			if got != tt.want || reason != tt.wantReason {
+				t.Errorf("restartPolicy() = %s, %q, want %s, %q", got, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestPlanRestarts(t *testing.T) {
+	tests := []struct {
+		name      string
+		workloads []*workload
+		// want lists the planned workloads in restart order
+		want []string
+	}{
+		{
+			name:      "no priorities keeps the order",
+			workloads: []*workload{testWorkload("a"), testWorkload("b"), testWorkload("c")},
+			want:      []string{"a", "b", "c"},
+		},
+		{
+			name: "higher priority first",
+			workloads: []*workload{
+				testWorkload("low", priorityAnnotation, "-5"),
+				testWorkload("default"),
+				testWorkload("high", priorityAnnotation, "10"),
+			},
+			want: []string{"high", "default", "low"},
+		},
+		{
+			name: "equal priorities keep the order",
+			workloads: []*workload{
+				testWorkload("a", priorityAnnotation, "1"),
+				testWorkload("b", priorityAnnotation, "2"),
+				testWorkload("c", priorityAnnotation, "1"),
+			},
+			want: []string{"b", "a", "c"},
+		},
+		{
+			name: "invalid priority counts as zero",
+			workloads: []*workload{
+				testWorkload("invalid", priorityAnnotation, "first"),
+				testWorkload("high", priorityAnnotation, "1"),
+			},
+			want: []string{"high", "invalid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{config: &Config{RestartStrategy: RestartStrategyRollout}}
+			var got []string
+			for _, plan := range uc.planRestarts(tt.workloads) {
+				got = append(got, plan.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planRestarts() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanRestartsSettings(t *testing.T) {
+	config := &Config{RestartStrategy: RestartStrategyRollout, RestartMaxWait: time.Minute}
+
+	tests := []struct {
+		name     string
+		workload *workload
+		want     WorkloadPlan
+	}{
+		{
+			name:     "defaults",
+			workload: testWorkload("tf2"),
+			want:     WorkloadPlan{Policy: WorkloadPolicyAuto, Strategy: RestartStrategyRollout, MaxWait: time.Minute},
+		},
+		{
+			name: "annotations",
+			workload: testWorkload("tf2",
+				policyAnnotation, "manual",
+				restartStrategyAnnotation, "evict",
+				priorityAnnotation, "3",
+				maxWaitAnnotation, "5m",
+			),
+			want: WorkloadPlan{Policy: WorkloadPolicyManual, Strategy: RestartStrategyEvict, Priority: 3, MaxWait: 5 * time.Minute, Reason: policyAnnotation + " annotation"},
+		},
+		{
+			name:     "invalid max-wait keeps the default",
+			workload: testWorkload("tf2", maxWaitAnnotation, "a while"),
+			want:     WorkloadPlan{Policy: WorkloadPolicyAuto, Strategy: RestartStrategyRollout, MaxWait: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{config: config}
+			plan := uc.planRestarts([]*workload{tt.workload})[0]
+			got := WorkloadPlan{Policy: plan.Policy, Strategy: plan.Strategy, Priority: plan.Priority, MaxWait: plan.MaxWait, Reason: plan.Reason}
+			if got != tt.want {
+				t.Errorf("planRestarts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
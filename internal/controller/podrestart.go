@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+// podPollInterval is how often pod state is polled during pod-level restarts
+const podPollInterval = 2 * time.Second
+
+// RestartStrategy selects how a workload is restarted
+type RestartStrategy string
+
+const (
+	// RestartStrategyRollout bumps the pod template so the workload's controller rolls its pods
+	RestartStrategyRollout RestartStrategy = "rollout"
+	// RestartStrategyPodDelete deletes pods one at a time, recreating bare pods from their spec
+	RestartStrategyPodDelete RestartStrategy = "pod-delete"
+	// RestartStrategyEvict evicts pods through the Eviction API, honouring PodDisruptionBudgets
+	RestartStrategyEvict RestartStrategy = "evict"
+	// RestartStrategyRecreate scales a Deployment or StatefulSet to zero and back, for ReadWriteOnce volumes
+	RestartStrategyRecreate RestartStrategy = "recreate"
+)
+
+// restartStrategy returns the effective strategy for a workload
+func (uc *UpdateController) restartStrategy(w *workload) RestartStrategy {
+	if w.Kind == "Pod" {
+		return RestartStrategyPodDelete
+	}
+
+	strategy := uc.config.RestartStrategy
+	if value, ok := w.Annotations[restartStrategyAnnotation]; ok {
+		if s, valid := parseRestartStrategy(value); valid {
+			strategy = s
+		} else {
+			klog.Warningf("Ignoring invalid %s annotation %q on %s", restartStrategyAnnotation, value, w)
+		}
+	}
+
+	// OnDelete StatefulSets ignore template changes, so a rollout would never land
+	if strategy == RestartStrategyRollout && w.groupKind().String() == "StatefulSet.apps" && w.Object != nil {
+		if updateType, _, _ := unstructured.NestedString(w.Object.Object, "spec", "updateStrategy", "type"); updateType == "OnDelete" {
+			return RestartStrategyPodDelete
+		}
+	}
+
+	return strategy
+}
+
+func parseRestartStrategy(value string) (RestartStrategy, bool) {
+	switch s := RestartStrategy(value); s {
+	case RestartStrategyRollout, RestartStrategyPodDelete, RestartStrategyEvict, RestartStrategyRecreate:
+		return s, true
+	}
+	return "", false
+}
+
+// deletePodsOneByOne deletes a workload's pods one at a time, waiting for each
+// to terminate and for its replacement, or the owning workload, to become ready
+func (uc *UpdateController) deletePodsOneByOne(ctx context.Context, w *workload) error {
+	// Bare pods and StatefulSet pods come back under the same name; other
+	// owners replace them under a new one, so their replica counts are watched
+	byName := w.Kind == "Pod" || w.groupKind().String() == "StatefulSet.apps"
+	byOwner := !byName && !w.Resource.Empty()
+
+	for _, pod := range w.Pods {
+		klog.Infof("Deleting pod %s/%s", pod.Namespace, pod.Name)
+
+		var replacement *corev1.Pod
+		if w.Kind == "Pod" {
+			replacement = newPodFrom(pod)
+		}
+
+		var ownerVersion string
+		if byOwner {
+			owner, err := uc.dynamicClient.Resource(w.Resource).Namespace(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get %s: %w", w, err)
+			}
+			ownerVersion = owner.GetResourceVersion()
+		}
+
+		// Default delete options apply the pod's own termination grace period
+		err := uc.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+		}
+
+		if err := uc.waitForPodGone(ctx, pod); err != nil {
+			return err
+		}
+
+		if replacement != nil {
+			if _, err := uc.clientset.CoreV1().Pods(pod.Namespace).Create(ctx, replacement, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to recreate pod %s: %w", pod.Name, err)
+			}
+			klog.V(2).Infof("Recreated bare pod %s/%s", pod.Namespace, pod.Name)
+		}
+
+		switch {
+		case byName:
+			if err := uc.waitForPodReady(ctx, pod.Namespace, pod.Name); err != nil {
+				return err
+			}
+		case byOwner:
+			if err := uc.waitForOwnerReady(ctx, w, ownerVersion); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitForPodGone blocks until the given pod instance no longer exists
+func (uc *UpdateController) waitForPodGone(ctx context.Context, pod *corev1.Pod) error {
+	grace := int64(corev1.DefaultTerminationGracePeriodSeconds)
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		grace = *pod.Spec.TerminationGracePeriodSeconds
+	}
+	deadline := time.Now().Add(time.Duration(grace)*time.Second + uc.config.PodReadyTimeout)
+
+	return uc.pollPod(ctx, pod.Namespace, pod.Name, deadline, func(current *corev1.Pod) bool {
+		return current == nil || current.UID != pod.UID
+	}, "terminate")
+}
+
+// waitForPodReady blocks until a pod with the given name is Ready
+func (uc *UpdateController) waitForPodReady(ctx context.Context, namespace, name string) error {
+	deadline := time.Now().Add(uc.config.PodReadyTimeout)
+
+	return uc.pollPod(ctx, namespace, name, deadline, func(current *corev1.Pod) bool {
+		return current != nil && current.DeletionTimestamp == nil && isPodReady(current)
+	}, "become ready")
+}
+
+// waitForOwnerReady blocks until the owning workload has changed since
+// resourceVersion, so its status counts the deletion, and is ready again
+func (uc *UpdateController) waitForOwnerReady(ctx context.Context, w *workload, resourceVersion string) error {
+	deadline := time.Now().Add(uc.config.PodReadyTimeout)
+	client := uc.dynamicClient.Resource(w.Resource).Namespace(w.Namespace)
+
+	for {
+		obj, err := client.Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", w, err)
+		}
+
+		if obj.GetResourceVersion() != resourceVersion && workloadReady(obj) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to become ready", w)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podPollInterval):
+		}
+	}
+}
+
+// pollPod polls a pod by name until done reports true; done receives nil once the pod is gone
+func (uc *UpdateController) pollPod(ctx context.Context, namespace, name string, deadline time.Time, done func(*corev1.Pod) bool, what string) error {
+	for {
+		current, err := uc.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			current, err = nil, nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get pod %s: %w", name, err)
+		}
+
+		if done(current) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for pod %s/%s to %s", namespace, name, what)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(podPollInterval):
+		}
+	}
+}
+
+// isPodReady reports whether a pod's Ready condition is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// newPodFrom builds a fresh copy of a bare pod from its metadata and spec
+func newPodFrom(pod *corev1.Pod) *corev1.Pod {
+	spec := pod.Spec.DeepCopy()
+	spec.EphemeralContainers = nil
+	// Let the scheduler place the copy instead of pinning it to a node that may be gone
+	spec.NodeName = ""
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pod.Name,
+			Namespace:   pod.Namespace,
+			Labels:      pod.Labels,
+			Annotations: pod.Annotations,
+		},
+		Spec: *spec,
+	}
+}
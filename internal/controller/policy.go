@@ -0,0 +1,513 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/steamcmd"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// PolicyReconciler runs an independent update loop for every GameUpdatePolicy
+type PolicyReconciler struct {
+	// config is the base every policy's config is derived from; configVersion
+	// counts reloads so controllers derived from an older base are updated.
+	// Both are guarded by policiesMu
+	config        *Config
+	configVersion int
+	clientset     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+
+	queue    workqueue.TypedRateLimitingInterface[string]
+	informer cache.SharedIndexInformer
+	recorder record.EventRecorder
+
+	// progress is shared by every policy's controller so one stream covers them all
+	progress *progressHub
+
+	// policies holds the controller built for each policy key; triggered marks
+	// keys the log watcher queued so their next pass checks even if not yet due
+	policies   map[string]*policyState
+	triggered  map[string]bool
+	policiesMu sync.Mutex
+}
+
+// policyState is the controller for one generation of a policy
+type policyState struct {
+	generation    int64
+	configVersion int
+	uc            *UpdateController
+	cancel        context.CancelFunc
+
+	// specErr is set when the spec could not be turned into a config
+	specErr error
+}
+
+// NewPolicyReconciler creates a reconciler for GameUpdatePolicy objects in all namespaces
+func NewPolicyReconciler(config *Config, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface) *PolicyReconciler {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+
+	return &PolicyReconciler{
+		config:        config,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "gameupdatepolicies"},
+		),
+		informer:  factory.ForResource(gameUpdatePolicyGVR).Informer(),
+		progress:  newProgressHub(),
+		policies:  make(map[string]*policyState),
+		triggered: make(map[string]bool),
+	}
+}
+
+// Run watches policies and reconciles them until the context is cancelled
+func (r *PolicyReconciler) Run(ctx context.Context) error {
+	klog.Info("GameUpdatePolicy reconciler started")
+	defer r.queue.ShutDown()
+
+	broadcaster := newEventBroadcaster(r.clientset)
+	defer broadcaster.Shutdown()
+	r.recorder = newEventRecorder(broadcaster)
+
+	_, err := r.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.enqueue,
+		UpdateFunc: func(oldObj, newObj any) {
+			// Status writes do not bump the generation and must not retrigger a check
+			if oldObj.(*unstructured.Unstructured).GetGeneration() != newObj.(*unstructured.Unstructured).GetGeneration() {
+				r.enqueue(newObj)
+			}
+		},
+		DeleteFunc: r.enqueue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch GameUpdatePolicies: %w", err)
+	}
+
+	go r.informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), r.informer.HasSynced) {
+		return fmt.Errorf("failed to sync GameUpdatePolicy cache")
+	}
+
+	r.policiesMu.Lock()
+	workers := max(r.config.PolicyWorkers, 1)
+	r.policiesMu.Unlock()
+	for range workers {
+		go wait.UntilWithContext(ctx, r.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+	klog.Info("GameUpdatePolicy reconciler stopping")
+	r.stopAll()
+	return ctx.Err()
+}
+
+// Reload switches to a new base config; each policy picks it up at its next
+// reconcile, which is queued now and never overlaps a running update
+func (r *PolicyReconciler) Reload(config *Config) {
+	r.policiesMu.Lock()
+	r.config = config
+	r.configVersion++
+	r.policiesMu.Unlock()
+
+	for _, key := range r.informer.GetIndexer().ListKeys() {
+		r.queue.Add(key)
+	}
+	klog.Info("Configuration reloaded, reapplying it to every policy")
+}
+
+func (r *PolicyReconciler) enqueue(obj any) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Warningf("Failed to get key for GameUpdatePolicy: %v", err)
+		return
+	}
+	r.queue.Add(key)
+}
+
+func (r *PolicyReconciler) runWorker(ctx context.Context) {
+	for r.processNextItem(ctx) {
+	}
+}
+
+func (r *PolicyReconciler) processNextItem(ctx context.Context) bool {
+	key, shutdown := r.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.queue.Done(key)
+
+	if err := r.reconcile(ctx, key); err != nil {
+		klog.Errorf("Failed to reconcile GameUpdatePolicy %s: %v", key, err)
+		r.queue.AddRateLimited(key)
+		return true
+	}
+
+	r.queue.Forget(key)
+	return true
+}
+
+// reconcile runs one update check for a policy and records the outcome in its status
+func (r *PolicyReconciler) reconcile(ctx context.Context, key string) error {
+	obj, exists, err := r.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		klog.Infof("GameUpdatePolicy %s deleted", key)
+		r.stop(key)
+		return nil
+	}
+
+	policy, err := policyFromUnstructured(obj.(*unstructured.Unstructured))
+	if err != nil {
+		return err
+	}
+
+	state := r.stateFor(ctx, key, policy)
+	if state.specErr != nil {
+		klog.Errorf("GameUpdatePolicy %s is invalid: %v", key, state.specErr)
+		// Nothing to retry until the spec changes
+		return r.updateStatus(ctx, policy, state, state.specErr)
+	}
+
+	// Manual commands replace the scheduled check; the work queue never runs a key twice at once
+	var checkErr error
+	if !state.uc.runPendingCommands(ctx) {
+		// Reloads and repeated queueing must not check before the interval is up;
+		// a new generation starts with a fresh controller that has never checked
+		if next := state.uc.lastCheck.Add(state.uc.config.CheckInterval); !r.takeTrigger(key) && time.Now().Before(next) {
+			klog.V(2).Infof("GameUpdatePolicy %s is not due for a check until %s", key, next.Format(time.RFC3339))
+			r.queue.AddAfter(key, time.Until(next))
+			return nil
+		}
+
+		klog.Infof("Reconciling GameUpdatePolicy %s", key)
+		checkErr = state.uc.performUpdateCheck(ctx)
+		if checkErr != nil {
+			klog.Errorf("Update check for %s failed: %v", key, checkErr)
+		}
+	}
+
+	if err := r.updateStatus(ctx, policy, state, checkErr); err != nil {
+		return err
+	}
+
+	r.queue.AddAfter(key, state.uc.config.CheckInterval)
+	return nil
+}
+
+// stateFor returns the controller for the policy's current generation,
+// replacing a stale one. A reload alone keeps the controller and its history
+// and only switches it to the newly derived config when that changed
+func (r *PolicyReconciler) stateFor(ctx context.Context, key string, policy *GameUpdatePolicy) *policyState {
+	r.policiesMu.Lock()
+	state, ok := r.policies[key]
+	base, version := r.config, r.configVersion
+	r.policiesMu.Unlock()
+	if ok && state.generation == policy.Generation {
+		if state.configVersion == version {
+			return state
+		}
+		if r.reapply(key, state, base, policy) {
+			r.policiesMu.Lock()
+			state.configVersion = version
+			r.policiesMu.Unlock()
+			return state
+		}
+	}
+
+	r.stop(key)
+
+	state = &policyState{generation: policy.Generation, configVersion: version, cancel: func() {}}
+	config, err := policyConfig(base, policy)
+	if err != nil {
+		state.specErr = err
+	} else {
+		steamClient := steamcmd.NewClient(config.SteamCMDPath, config.SteamApp, config.SteamAppID, config.GameMountPath, config.UpdateScript)
+		steamClient.SetBranch(config.SteamBranch)
+
+		state.uc = NewUpdateController(config, r.clientset, r.dynamicClient, steamClient)
+		state.uc.recorder = r.recorder
+		state.uc.eventTarget = policyReference(policy)
+		state.uc.progress = r.progress
+		state.uc.start(ctx, true)
+
+		if config.LogTriggerEnabled {
+			watchCtx, cancel := context.WithCancel(ctx)
+			state.cancel = cancel
+			r.watchLogs(watchCtx, key, state.uc)
+		}
+	}
+
+	r.policiesMu.Lock()
+	r.policies[key] = state
+	r.policiesMu.Unlock()
+	return state
+}
+
+// reapply switches a running controller to the config derived from a reloaded
+// base, returning false when the controller has to be rebuilt instead
+func (r *PolicyReconciler) reapply(key string, state *policyState, base *Config, policy *GameUpdatePolicy) bool {
+	if state.uc == nil {
+		return false
+	}
+	config, err := policyConfig(base, policy)
+	if err != nil {
+		return false
+	}
+	changes := diffConfig(state.uc.currentConfig(), config)
+	if len(changes) == 0 {
+		klog.V(2).Infof("Reload does not change the config of GameUpdatePolicy %s", key)
+		return true
+	}
+	for _, change := range changes {
+		klog.Infof("GameUpdatePolicy %s %s", key, change)
+	}
+	state.uc.applyConfig(config)
+	return true
+}
+
+// watchLogs queues a policy whenever its servers log that they are out of date
+func (r *PolicyReconciler) watchLogs(ctx context.Context, key string, uc *UpdateController) {
+	watcher := newLogWatcher(uc)
+	go watcher.run(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watcher.trigger:
+				r.policiesMu.Lock()
+				r.triggered[key] = true
+				r.policiesMu.Unlock()
+				r.queue.Add(key)
+			}
+		}
+	}()
+}
+
+// takeTrigger reports and clears whether the log watcher queued key
+func (r *PolicyReconciler) takeTrigger(key string) bool {
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	triggered := r.triggered[key]
+	delete(r.triggered, key)
+	return triggered
+}
+
+func (r *PolicyReconciler) stop(key string) {
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	if state, ok := r.policies[key]; ok {
+		state.cancel()
+		delete(r.policies, key)
+	}
+	delete(r.triggered, key)
+}
+
+func (r *PolicyReconciler) stopAll() {
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	for key, state := range r.policies {
+		state.cancel()
+		delete(r.policies, key)
+	}
+}
+
+// updateStatus writes the policy's builds, timestamps and conditions
+func (r *PolicyReconciler) updateStatus(ctx context.Context, policy *GameUpdatePolicy, state *policyState, checkErr error) error {
+	client := r.dynamicClient.Resource(gameUpdatePolicyGVR).Namespace(policy.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.Get(ctx, policy.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		latest, err := policyFromUnstructured(current)
+		if err != nil {
+			return err
+		}
+
+		status := latest.Status
+		status.ObservedGeneration = state.generation
+		setPolicyStatus(&status, state, checkErr)
+
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedField(current.Object, content, "status"); err != nil {
+			return err
+		}
+
+		_, err = client.UpdateStatus(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// setPolicyStatus fills status from the outcome of a reconcile
+func setPolicyStatus(status *GameUpdatePolicyStatus, state *policyState, checkErr error) {
+	now := metav1.Now()
+	generation := state.generation
+
+	if state.specErr != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type: PolicyConditionReady, Status: metav1.ConditionFalse, ObservedGeneration: generation,
+			Reason: "InvalidSpec", Message: state.specErr.Error(),
+		})
+		return
+	}
+
+	uc := state.uc
+	status.LastCheckTime = &now
+	if installed, _, err := uc.steamClient.InstalledBuild(); err == nil {
+		status.InstalledBuild = installed
+	}
+	if latest := uc.steamClient.LatestBuild(); latest != "" {
+		status.LatestBuild = latest
+	}
+
+	if checkErr != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type: PolicyConditionReady, Status: metav1.ConditionFalse, ObservedGeneration: generation,
+			Reason: "CheckFailed", Message: checkErr.Error(),
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type: PolicyConditionReady, Status: metav1.ConditionTrue, ObservedGeneration: generation,
+			Reason: "CheckSucceeded", Message: "Last update check succeeded",
+		})
+	}
+
+	upToDate := metav1.Condition{Type: PolicyConditionUpToDate, ObservedGeneration: generation}
+	switch {
+	case status.InstalledBuild != "" && status.InstalledBuild == status.LatestBuild:
+		upToDate.Status, upToDate.Reason = metav1.ConditionTrue, "UpToDate"
+		upToDate.Message = "Build " + status.InstalledBuild + " is installed"
+	case uc.updateDeferred:
+		upToDate.Status, upToDate.Reason = metav1.ConditionFalse, "OutsideMaintenanceWindow"
+		upToDate.Message = "Update to build " + status.LatestBuild + " is waiting for a maintenance window"
+	default:
+		upToDate.Status, upToDate.Reason = metav1.ConditionFalse, "UpdateAvailable"
+		upToDate.Message = fmt.Sprintf("Installed build %q, latest build %q", status.InstalledBuild, status.LatestBuild)
+	}
+	meta.SetStatusCondition(&status.Conditions, upToDate)
+
+	if result := uc.lastResult; result != nil {
+		if result.Success {
+			finishedAt := metav1.NewTime(result.FinishedAt)
+			status.LastUpdateTime = &finishedAt
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type: PolicyConditionDegraded, Status: metav1.ConditionFalse, ObservedGeneration: generation,
+				Reason: "UpdateSucceeded", Message: "Last update and restart succeeded",
+			})
+		} else {
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type: PolicyConditionDegraded, Status: metav1.ConditionTrue, ObservedGeneration: generation,
+				Reason: "UpdateFailed", Message: result.Error,
+			})
+		}
+	}
+}
+
+// Healthy reports whether any policy is wedged inside steamcmd
+func (r *PolicyReconciler) Healthy() error {
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	for key, state := range r.policies {
+		if state.uc == nil {
+			continue
+		}
+		// Policy checks are scheduled by the work queue, so there is no loop tick to check
+		if err := state.uc.heartbeat.check(0); err != nil {
+			return fmt.Errorf("policy %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Ready reports whether the Kubernetes API is reachable, policies are synced
+// and every policy's volume and steamcmd are usable
+func (r *PolicyReconciler) Ready(ctx context.Context) error {
+	if err := checkAPIServer(ctx, r.clientset); err != nil {
+		return err
+	}
+	if !r.informer.HasSynced() {
+		return fmt.Errorf("GameUpdatePolicy cache not synced")
+	}
+
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	for key, state := range r.policies {
+		if state.uc == nil {
+			continue
+		}
+		if err := checkInstallPaths(state.uc.currentConfig()); err != nil {
+			return fmt.Errorf("policy %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Statuses returns the status snapshot of every valid policy
+func (r *PolicyReconciler) Statuses() []Status {
+	r.policiesMu.Lock()
+	defer r.policiesMu.Unlock()
+
+	statuses := make([]Status, 0, len(r.policies))
+	for _, state := range r.policies {
+		if state.uc != nil {
+			statuses = append(statuses, state.uc.Status())
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Policy < statuses[j].Policy })
+	return statuses
+}
+
+// SubscribeProgress streams live progress events of every policy until the returned function is called
+func (r *PolicyReconciler) SubscribeProgress() (<-chan ProgressEvent, func()) {
+	return r.progress.subscribe()
+}
+
+// Submit queues a command for the named namespace/name policy and schedules it
+func (r *PolicyReconciler) Submit(policy string, command Command) error {
+	r.policiesMu.Lock()
+	state, ok := r.policies[policy]
+	r.policiesMu.Unlock()
+	if !ok || state.uc == nil {
+		return fmt.Errorf("%w %q", ErrUnknownPolicy, policy)
+	}
+
+	if err := state.uc.submit(command); err != nil {
+		return err
+	}
+	r.queue.Add(policy)
+	return nil
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gameUpdatePolicyGVR is the GameUpdatePolicy custom resource
+var gameUpdatePolicyGVR = schema.GroupVersionResource{Group: "updatecontroller.udl.tf", Version: "v1alpha1", Resource: "gameupdatepolicies"}
+
+// Condition types reported on GameUpdatePolicy status
+const (
+	// PolicyConditionReady is true when the spec is valid and the last update check succeeded
+	PolicyConditionReady = "Ready"
+	// PolicyConditionUpToDate is true when the installed build matches the latest build
+	PolicyConditionUpToDate = "UpToDate"
+	// PolicyConditionDegraded is true when the last update or restart failed
+	PolicyConditionDegraded = "Degraded"
+)
+
+// GameUpdatePolicy configures updates for one Steam app install and the workloads running it
+type GameUpdatePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GameUpdatePolicySpec   `json:"spec"`
+	Status GameUpdatePolicyStatus `json:"status,omitempty"`
+}
+
+// GameUpdatePolicySpec is the desired update behaviour of a policy
+type GameUpdatePolicySpec struct {
+	AppID  string       `json:"appId"`
+	App    string       `json:"app,omitempty"`
+	Branch string       `json:"branch,omitempty"`
+	Volume PolicyVolume `json:"volume"`
+	Target PolicyTarget `json:"target"`
+
+	CheckInterval        metav1.Duration      `json:"checkInterval,omitempty"`
+	Windows              []MaintenanceWindow  `json:"windows,omitempty"`
+	RestartStrategy      RestartStrategy      `json:"restartStrategy,omitempty"`
+	PartialRestartPolicy PartialRestartPolicy `json:"partialRestartPolicy,omitempty"`
+	Notifications        []NotificationSink   `json:"notifications,omitempty"`
+
+	// DryRun only plans updates and restarts for this policy; DRY_RUN applies it to every policy
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// PolicyVolume is where the policy's game files are mounted in the controller
+type PolicyVolume struct {
+	MountPath string `json:"mountPath"`
+}
+
+// PolicyTarget selects the pods restarted after an update; no namespaces means the policy's own
+type PolicyTarget struct {
+	Namespaces        []string `json:"namespaces,omitempty"`
+	NamespaceSelector string   `json:"namespaceSelector,omitempty"`
+	PodSelector       string   `json:"podSelector"`
+	FieldSelector     string   `json:"fieldSelector,omitempty"`
+}
+
+// GameUpdatePolicyStatus is the observed state of a policy
+type GameUpdatePolicyStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	InstalledBuild     string             `json:"installedBuild,omitempty"`
+	LatestBuild        string             `json:"latestBuild,omitempty"`
+	LastCheckTime      *metav1.Time       `json:"lastCheckTime,omitempty"`
+	LastUpdateTime     *metav1.Time       `json:"lastUpdateTime,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+func policyFromUnstructured(obj *unstructured.Unstructured) (*GameUpdatePolicy, error) {
+	policy := &GameUpdatePolicy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, policy); err != nil {
+		return nil, fmt.Errorf("failed to decode GameUpdatePolicy %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return policy, nil
+}
+
+// policyConfig derives a controller config for a policy from the base config
+func policyConfig(base *Config, policy *GameUpdatePolicy) (*Config, error) {
+	spec := policy.Spec
+	if spec.AppID == "" {
+		return nil, fmt.Errorf("spec.appId is required")
+	}
+	if spec.Volume.MountPath == "" {
+		return nil, fmt.Errorf("spec.volume.mountPath is required")
+	}
+	if spec.Target.PodSelector == "" {
+		return nil, fmt.Errorf("spec.target.podSelector is required")
+	}
+
+	config := *base
+	config.PolicyName = policy.Namespace + "/" + policy.Name
+	config.SteamAppID = spec.AppID
+	config.SteamBranch = spec.Branch
+	config.GameMountPath = spec.Volume.MountPath
+	if spec.App != "" {
+		config.SteamApp = spec.App
+	}
+
+	config.Namespaces = spec.Target.Namespaces
+	config.NamespaceSelector = spec.Target.NamespaceSelector
+	config.NamespacePodSelectors = nil
+	config.PodSelector = spec.Target.PodSelector
+	config.FieldSelector = spec.Target.FieldSelector
+	if len(config.Namespaces) == 0 && config.NamespaceSelector == "" {
+		config.Namespaces = []string{policy.Namespace}
+	}
+
+	if spec.CheckInterval.Duration > 0 {
+		config.CheckInterval = spec.CheckInterval.Duration
+	}
+
+	for _, window := range spec.Windows {
+		if err := window.validate(); err != nil {
+			return nil, fmt.Errorf("invalid window %s: %w", window, err)
+		}
+	}
+	config.MaintenanceWindows = spec.Windows
+
+	if spec.RestartStrategy != "" {
+		strategy, ok := parseRestartStrategy(string(spec.RestartStrategy))
+		if !ok {
+			return nil, fmt.Errorf("unknown restartStrategy %q", spec.RestartStrategy)
+		}
+		config.RestartStrategy = strategy
+	}
+
+	switch spec.PartialRestartPolicy {
+	case "":
+	case PartialRestartAll, PartialRestartAny:
+		config.PartialRestartPolicy = spec.PartialRestartPolicy
+	default:
+		return nil, fmt.Errorf("unknown partialRestartPolicy %q", spec.PartialRestartPolicy)
+	}
+
+	if spec.DryRun {
+		config.DryRun = true
+	}
+
+	// Policy sinks are notified in addition to the controller-wide webhooks
+	for _, sink := range spec.Notifications {
+		if err := sink.validate(); err != nil {
+			return nil, err
+		}
+	}
+	config.Notifications = append(append([]NotificationSink{}, base.Notifications...), spec.Notifications...)
+
+	// The spec can break rules the base config satisfied, e.g. a checkInterval
+	// no longer than RETRY_DELAY or a branch equal to ROLLBACK_BRANCH
+	if problems := config.validatePolicy(); len(problems) > 0 {
+		return nil, fmt.Errorf("spec conflicts with the controller settings: %s", strings.Join(problems, "; "))
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/steamcmd"
+)
+
+// progressBuffer is how many events a slow subscriber may fall behind before events are dropped
+const progressBuffer = 64
+
+// ProgressEventType is the kind of a live progress event
+type ProgressEventType string
+
+const (
+	// ProgressPhase reports the update moving to a new phase
+	ProgressPhase ProgressEventType = "phase"
+	// ProgressDownload reports download or verification progress parsed from steamcmd
+	ProgressDownload ProgressEventType = "download"
+	// ProgressRestart reports a workload restart starting or finishing
+	ProgressRestart ProgressEventType = "restart"
+)
+
+// ProgressEvent is one live progress update, streamed by the admin API
+type ProgressEvent struct {
+	Type        ProgressEventType `json:"type"`
+	Policy      string            `json:"policy,omitempty"`
+	App         string            `json:"app"`
+	Time        time.Time         `json:"time"`
+	Phase       UpdatePhase       `json:"phase"`
+	TargetBuild string            `json:"targetBuild,omitempty"`
+	Download    *DownloadProgress `json:"download,omitempty"`
+	Restart     *RestartProgress  `json:"restart,omitempty"`
+}
+
+// DownloadProgress is the progress of the running steamcmd stage
+type DownloadProgress struct {
+	Stage          string  `json:"stage"`
+	State          string  `json:"state"`
+	Percent        float64 `json:"percent"`
+	BytesDone      int64   `json:"bytesDone"`
+	BytesTotal     int64   `json:"bytesTotal"`
+	BytesPerSecond float64 `json:"bytesPerSecond,omitempty"`
+	ETASeconds     int64   `json:"etaSeconds,omitempty"`
+}
+
+// RestartProgress is the state of one workload restart and of the restart as a whole
+type RestartProgress struct {
+	Namespace string          `json:"namespace"`
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Strategy  RestartStrategy `json:"strategy"`
+	State     string          `json:"state"`
+	Error     string          `json:"error,omitempty"`
+	Done      int             `json:"done"`
+	Total     int             `json:"total"`
+}
+
+// progressHub fans progress events out to subscribers without ever blocking the publisher
+type progressHub struct {
+	mu          sync.Mutex
+	subscribers map[chan ProgressEvent]struct{}
+
+	// latest holds the last phase and download event per policy, replayed to new subscribers
+	latest map[string]ProgressEvent
+}
+
+func newProgressHub() *progressHub {
+	return &progressHub{
+		subscribers: make(map[chan ProgressEvent]struct{}),
+		latest:      make(map[string]ProgressEvent),
+	}
+}
+
+// subscribe returns a channel of events, starting with the current phase and
+// download progress, and a function that ends the subscription
+func (h *progressHub) subscribe() (<-chan ProgressEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan ProgressEvent, progressBuffer)
+	for _, event := range h.latest {
+		ch <- event
+	}
+	h.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *progressHub) publish(event ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch event.Type {
+	case ProgressPhase:
+		h.latest[event.Policy+"/"+string(ProgressPhase)] = event
+		// Progress of a finished stage is stale once the phase changes
+		delete(h.latest, event.Policy+"/"+string(ProgressDownload))
+	case ProgressDownload:
+		h.latest[event.Policy+"/"+string(ProgressDownload)] = event
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Slow subscribers miss events rather than stalling steamcmd or a restart
+		}
+	}
+}
+
+// SubscribeProgress streams live progress events until the returned function is called
+func (uc *UpdateController) SubscribeProgress() (<-chan ProgressEvent, func()) {
+	return uc.progress.subscribe()
+}
+
+// progressEvent builds an event stamped with the controller's current phase
+func (uc *UpdateController) progressEvent(eventType ProgressEventType) ProgressEvent {
+	return ProgressEvent{
+		Type:        eventType,
+		Policy:      uc.config.PolicyName,
+		App:         uc.config.SteamApp,
+		Time:        time.Now(),
+		Phase:       uc.state.Phase,
+		TargetBuild: uc.state.TargetBuild,
+	}
+}
+
+// downloadTracker estimates throughput and ETA for the running steamcmd stage
+type downloadTracker struct {
+	mu sync.Mutex
+
+	// first is the first sample of the stage, which the rate is averaged from
+	stage      string
+	firstAt    time.Time
+	firstBytes int64
+}
+
+// reset starts tracking a new steamcmd stage
+func (t *downloadTracker) reset(stage string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stage = stage
+	t.firstAt = time.Time{}
+}
+
+// estimate turns a steamcmd progress line into download progress with rate and ETA
+func (t *downloadTracker) estimate(p steamcmd.Progress) *DownloadProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress := &DownloadProgress{
+		Stage:      p.Stage,
+		State:      p.State,
+		Percent:    p.Percent,
+		BytesDone:  p.BytesDone,
+		BytesTotal: p.BytesTotal,
+	}
+
+	// steamcmd restarts its byte count when it moves from downloading to verifying
+	if t.stage != p.Stage || t.firstAt.IsZero() || p.BytesDone < t.firstBytes {
+		t.stage = p.Stage
+		t.firstAt = time.Now()
+		t.firstBytes = p.BytesDone
+		return progress
+	}
+
+	elapsed := time.Since(t.firstAt).Seconds()
+	if elapsed <= 0 || p.BytesDone <= t.firstBytes {
+		return progress
+	}
+
+	progress.BytesPerSecond = float64(p.BytesDone-t.firstBytes) / elapsed
+	if remaining := p.BytesTotal - p.BytesDone; remaining > 0 {
+		progress.ETASeconds = int64(float64(remaining) / progress.BytesPerSecond)
+	}
+	return progress
+}
+
+// reportDownload publishes a steamcmd progress line; it runs on steamcmd's output goroutine
+func (uc *UpdateController) reportDownload(p steamcmd.Progress) {
+	event := uc.progressEvent(ProgressDownload)
+	event.Download = uc.downloads.estimate(p)
+	uc.progress.publish(event)
+}
+
+// reportRestart publishes the state of a workload restart along with the overall count
+func (uc *UpdateController) reportRestart(plan *WorkloadPlan, state string, result *RestartResult, err error) {
+	event := uc.progressEvent(ProgressRestart)
+	event.Restart = &RestartProgress{
+		Namespace: plan.Namespace,
+		Kind:      plan.Kind,
+		Name:      plan.Name,
+		Strategy:  plan.Strategy,
+		State:     state,
+		Done:      len(result.Succeeded) + len(result.Failed) + len(result.Skipped),
+		Total:     len(result.Plan),
+	}
+	if err != nil {
+		event.Restart.Error = err.Error()
+	}
+	uc.progress.publish(event)
+}
@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+// recreatePollInterval is how often scale progress is polled during a recreate
+const recreatePollInterval = 5 * time.Second
+
+// restoreTimeout bounds scaling a workload back after a failed recreate
+const restoreTimeout = 30 * time.Second
+
+// recreateResources are the workload kinds the recreate strategy can scale
+var recreateResources = map[string]schema.GroupVersionResource{
+	"Deployment.apps":  {Group: "apps", Version: "v1", Resource: "deployments"},
+	"StatefulSet.apps": {Group: "apps", Version: "v1", Resource: "statefulsets"},
+}
+
+// recreateWorkload scales a workload to zero, waits for every pod to
+// terminate so ReadWriteOnce volumes are released, then scales it back up.
+// The original replica count is recorded on the workload first so an
+// interrupted recreate can be finished by resumeRecreates
+func (uc *UpdateController) recreateWorkload(ctx context.Context, w *workload) error {
+	gvr, ok := recreateResources[w.groupKind().String()]
+	if !ok {
+		return fmt.Errorf("recreate strategy does not support %s", w.groupKind())
+	}
+	client := uc.dynamicClient.Resource(gvr).Namespace(w.Namespace)
+
+	obj, err := client.Get(ctx, w.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", w, err)
+	}
+
+	replicas, recorded := recordedReplicas(obj)
+	if !recorded {
+		current, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			current = 1
+		}
+		if err := markRecreating(ctx, client, w.Name, &current); err != nil {
+			return err
+		}
+		klog.Infof("Recorded %d replicas for %s", current, w)
+		replicas = current
+	}
+
+	klog.Infof("Scaling %s to 0 replicas", w)
+	err = scaleTo(ctx, client, w.Name, 0)
+	if err == nil {
+		err = uc.waitForPodsTerminated(ctx, client, w)
+	}
+	if err == nil {
+		err = uc.finishRecreate(ctx, client, w.String(), w.Name, replicas)
+	}
+	if err != nil {
+		restoreReplicas(ctx, client, w.String(), w.Name, replicas)
+	}
+	return err
+}
+
+// restoreReplicas scales a workload back after a failed recreate so its game
+// servers do not stay down until the next controller start. The recreate
+// markers are kept for resumeRecreates when the scale fails
+func restoreReplicas(ctx context.Context, client dynamic.ResourceInterface, display, name string, replicas int64) {
+	// Restore even when the failure was a shutdown cancelling ctx
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), restoreTimeout)
+	defer cancel()
+
+	klog.Warningf("Recreate of %s failed, scaling it back to %d replicas", display, replicas)
+	if err := scaleTo(ctx, client, name, replicas); err != nil {
+		klog.Errorf("Failed to restore %s to %d replicas, retrying on the next start: %v", display, replicas, err)
+		return
+	}
+	if err := markRecreating(ctx, client, name, nil); err != nil {
+		klog.Warningf("Failed to clear recreate markers on %s: %v", display, err)
+	}
+}
+
+// finishRecreate scales a workload back to its original size, waits for it to
+// become ready and clears the recreate markers
+func (uc *UpdateController) finishRecreate(ctx context.Context, client dynamic.ResourceInterface, display, name string, replicas int64) error {
+	klog.Infof("Scaling %s back to %d replicas", display, replicas)
+	if err := scaleTo(ctx, client, name, replicas); err != nil {
+		return err
+	}
+
+	if err := uc.waitForReplicasReady(ctx, client, display, name, replicas); err != nil {
+		return err
+	}
+
+	return markRecreating(ctx, client, name, nil)
+}
+
+// resumeRecreates restores the replica count of workloads left scaled down by an interrupted recreate
+func (uc *UpdateController) resumeRecreates(ctx context.Context) {
+	namespaces, err := uc.targetNamespaces(ctx)
+	if err != nil {
+		klog.Warningf("Resuming recreates in static namespaces only: %v", err)
+	}
+
+	for _, ns := range namespaces {
+		for _, gvr := range recreateResources {
+			uc.resumeRecreatesOf(ctx, uc.dynamicClient.Resource(gvr).Namespace(ns))
+		}
+	}
+}
+
+// resumeRecreatesOf finishes the interrupted recreates of one resource in one namespace
+func (uc *UpdateController) resumeRecreatesOf(ctx context.Context, client dynamic.ResourceInterface) {
+	list, err := client.List(ctx, metav1.ListOptions{LabelSelector: recreatingLabel + "=true"})
+	if err != nil {
+		klog.Warningf("Failed to list workloads with interrupted recreates: %v", err)
+		return
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		display := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+
+		replicas, ok := recordedReplicas(obj)
+		if !ok {
+			klog.Warningf("%s is marked as recreating without a valid %s annotation", display, originalReplicasAnnotation)
+			continue
+		}
+
+		if uc.config.DryRun {
+			uc.dryRunSkip("restore %s to %d replicas after an interrupted recreate", display, replicas)
+			continue
+		}
+
+		klog.Infof("Resuming interrupted recreate of %s", display)
+		if err := uc.finishRecreate(ctx, client, display, obj.GetName(), replicas); err != nil {
+			klog.Errorf("Failed to restore %s to %d replicas: %v", display, replicas, err)
+		}
+	}
+}
+
+// waitForPodsTerminated blocks until no pods match the workload's selector
+func (uc *UpdateController) waitForPodsTerminated(ctx context.Context, client dynamic.ResourceInterface, w *workload) error {
+	scale, err := client.Get(ctx, w.Name, metav1.GetOptions{}, "scale")
+	if err != nil {
+		return fmt.Errorf("failed to get scale of %s: %w", w, err)
+	}
+
+	selector, _, _ := unstructured.NestedString(scale.Object, "status", "selector")
+	if selector == "" {
+		return fmt.Errorf("%s has no pod selector", w)
+	}
+
+	deadline := time.Now().Add(uc.config.PodReadyTimeout)
+	for {
+		pods, err := uc.clientset.CoreV1().Pods(w.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return fmt.Errorf("failed to list pods of %s: %w", w, err)
+		}
+
+		if len(pods.Items) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %d pods of %s to terminate", len(pods.Items), w)
+		}
+
+		klog.V(2).Infof("Waiting for %d pods of %s to terminate", len(pods.Items), w)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(recreatePollInterval):
+		}
+	}
+}
+
+// waitForReplicasReady blocks until a workload reports the given number of ready replicas
+func (uc *UpdateController) waitForReplicasReady(ctx context.Context, client dynamic.ResourceInterface, display, name string, replicas int64) error {
+	deadline := time.Now().Add(uc.config.PodReadyTimeout)
+	for {
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", display, err)
+		}
+
+		observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+		ready, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if observed >= obj.GetGeneration() && ready >= replicas {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to have %d ready replicas (has %d)", display, replicas, ready)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(recreatePollInterval):
+		}
+	}
+}
+
+// scaleTo sets a workload's replica count through its scale subresource
+func scaleTo(ctx context.Context, client dynamic.ResourceInterface, name string, replicas int64) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	if _, err := client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale"); err != nil {
+		return fmt.Errorf("failed to scale %s to %d: %w", name, replicas, err)
+	}
+	return nil
+}
+
+// markRecreating records the original replica count on a workload, or clears the markers when replicas is nil
+func markRecreating(ctx context.Context, client dynamic.ResourceInterface, name string, replicas *int64) error {
+	var label, annotation any
+	if replicas != nil {
+		label = "true"
+		annotation = strconv.FormatInt(*replicas, 10)
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels":      map[string]any{recreatingLabel: label},
+			"annotations": map[string]any{originalReplicasAnnotation: annotation},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build recreate marker patch: %w", err)
+	}
+
+	if _, err := client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update recreate markers on %s: %w", name, err)
+	}
+	return nil
+}
+
+// recordedReplicas reads the original replica count left by markRecreating
+func recordedReplicas(obj *unstructured.Unstructured) (int64, bool) {
+	value, ok := obj.GetAnnotations()[originalReplicasAnnotation]
+	if !ok {
+		return 0, false
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || replicas < 0 {
+		return 0, false
+	}
+	return replicas, true
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/metrics"
+	"k8s.io/klog/v2"
+)
+
+// configPollInterval is how often the config file is checked for changes; a
+// mounted ConfigMap is swapped through a symlink, which polling sees reliably
+const configPollInterval = 10 * time.Second
+
+// restartOnlySettings are Config fields read once at startup or tied to the
+// install on the volume; a reload keeps their current value
+var restartOnlySettings = map[string]bool{
+	"HTTPAddr":          true,
+	"AdminToken":        true,
+	"PoliciesEnabled":   true,
+	"PolicyWorkers":     true,
+	"LogTriggerEnabled": true,
+	"SteamApp":          true,
+	"SteamAppID":        true,
+	"GameMountPath":     true,
+	"StateFile":         true,
+	// Startup recovery dry-run skipped only runs when the controller starts
+	"DryRun": true,
+}
+
+// secretSettings are Config fields whose values are never logged
+var secretSettings = map[string]bool{
+	"AdminToken":    true,
+	"Notifications": true,
+}
+
+// configChange is one setting that differs between two configs
+type configChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c configChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// diffConfig lists the settings that differ between old and new, hiding secret values
+func diffConfig(old, new *Config) []configChange {
+	var changes []configChange
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := range oldValue.NumField() {
+		field := oldValue.Type().Field(i).Name
+		before, after := fmt.Sprint(oldValue.Field(i).Interface()), fmt.Sprint(newValue.Field(i).Interface())
+		if before == after {
+			continue
+		}
+		if secretSettings[field] {
+			before, after = "(hidden)", "(changed)"
+		}
+		changes = append(changes, configChange{Field: field, Old: before, New: after})
+	}
+	return changes
+}
+
+// WatchConfig polls the config file at path until ctx is done. Each change
+// that loads and validates is logged as a diff against current and handed to
+// apply; an invalid file is rejected and the current configuration stays in use
+func WatchConfig(ctx context.Context, path string, current *Config, apply func(*Config)) {
+	klog.Infof("Watching %s for configuration changes", path)
+	last, err := os.ReadFile(path)
+	if err != nil {
+		klog.Warningf("Failed to read config file %s: %v", path, err)
+	}
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			klog.Warningf("Failed to read config file %s: %v", path, err)
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+
+		next, err := LoadConfig(path)
+		if err != nil {
+			metrics.ConfigReloads.WithLabelValues(metrics.Result(false)).Inc()
+			klog.Errorf("Rejected change to %s, keeping the current configuration: %v", path, err)
+			continue
+		}
+
+		next = reloadable(current, next)
+		changes := diffConfig(current, next)
+		if len(changes) == 0 {
+			klog.V(2).Infof("Config file %s changed without changing any setting", path)
+			continue
+		}
+
+		metrics.ConfigReloads.WithLabelValues(metrics.Result(true)).Inc()
+		klog.Infof("Reloading configuration from %s:", path)
+		for _, change := range changes {
+			klog.Infof("  %s", change)
+		}
+		current = next
+		apply(next)
+	}
+}
+
+// reloadable returns next with every restart-only setting reset to its
+// current value, warning about each one that was changed
+func reloadable(current, next *Config) *Config {
+	currentValue, nextValue := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for _, change := range diffConfig(current, next) {
+		if !restartOnlySettings[change.Field] {
+			continue
+		}
+		klog.Warningf("Ignoring change to %s until the controller restarts", change.Field)
+		nextValue.FieldByName(change.Field).Set(currentValue.FieldByName(change.Field))
+	}
+	return next
+}
+
+// Reload hands a new configuration to the main loop, which applies it
+// between update cycles; only the newest pending configuration is kept
+func (uc *UpdateController) Reload(config *Config) {
+	select {
+	case <-uc.reloads:
+	default:
+	}
+	uc.reloads <- config
+}
+
+// applyConfig switches to config and passes its steamcmd settings to the client
+func (uc *UpdateController) applyConfig(config *Config) {
+	uc.configMu.Lock()
+	uc.config = config
+	uc.configMu.Unlock()
+
+	uc.steamClient.SetSteamCMDPath(config.SteamCMDPath)
+	uc.steamClient.SetUpdateScript(config.UpdateScript)
+	uc.steamClient.SetBranch(config.SteamBranch)
+	uc.steamClient.SetTimeout(config.SteamCMDTimeout)
+	uc.publishStatus()
+	klog.Info("Configuration reloaded")
+}
+
+// currentConfig returns the config for callers outside the main loop
+func (uc *UpdateController) currentConfig() *Config {
+	uc.configMu.RLock()
+	defer uc.configMu.RUnlock()
+	return uc.config
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []configChange
+	}{
+		{
+			name:   "unchanged",
+			change: func(*Config) {},
+		},
+		{
+			name:   "duration",
+			change: func(c *Config) { c.CheckInterval = time.Hour },
+			want:   []configChange{{Field: "CheckInterval", Old: "30m0s", New: "1h0m0s"}},
+		},
+		{
+			name: "several settings in field order",
+			change: func(c *Config) {
+				c.Namespaces = []string{"game-servers", "staging"}
+				c.SteamBranch = "prerelease"
+			},
+			want: []configChange{
+				{Field: "SteamBranch", Old: "", New: "prerelease"},
+				{Field: "Namespaces", Old: "[game-servers]", New: "[game-servers staging]"},
+			},
+		},
+		{
+			name:   "secret hidden",
+			change: func(c *Config) { c.AdminToken = "s3cret" },
+			want:   []configChange{{Field: "AdminToken", Old: "(hidden)", New: "(changed)"}},
+		},
+		{
+			name:   "webhooks hidden",
+			change: func(c *Config) { c.Notifications = []NotificationSink{{URL: "https://hooks.example.com/t0ken"}} },
+			want:   []configChange{{Field: "Notifications", Old: "(hidden)", New: "(changed)"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &Config{CheckInterval: 30 * time.Minute, Namespaces: []string{"game-servers"}}
+			next := *old
+			tt.change(&next)
+
+			if got := diffConfig(old, &next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		field  func(*Config) any
+		want   any
+	}{
+		{
+			name:   "reloadable setting applied",
+			change: func(c *Config) { c.CheckInterval = time.Hour },
+			field:  func(c *Config) any { return c.CheckInterval },
+			want:   time.Hour,
+		},
+		{
+			name:   "restart-only setting kept",
+			change: func(c *Config) { c.GameMountPath = "/srv/other" },
+			field:  func(c *Config) any { return c.GameMountPath },
+			want:   "/tf",
+		},
+		{
+			name: "mixed change keeps only the reloadable part",
+			change: func(c *Config) {
+				c.HTTPAddr = ":9090"
+				c.SteamBranch = "prerelease"
+			},
+			field: func(c *Config) any { return []any{c.HTTPAddr, c.SteamBranch} },
+			want:  []any{":8080", "prerelease"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := &Config{CheckInterval: 30 * time.Minute, GameMountPath: "/tf", HTTPAddr: ":8080"}
+			next := *current
+			tt.change(&next)
+
+			if got := tt.field(reloadable(current, &next)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reloadable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadSettingsAreConfigFields(t *testing.T) {
+	config := reflect.TypeOf(Config{})
+	for _, settings := range []map[string]bool{restartOnlySettings, secretSettings} {
+		for field := range settings {
+			if _, ok := config.FieldByName(field); !ok {
+				t.Errorf("%s is not a Config field", field)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// repairInstall runs a validate-and-repair pass when the manifest shows an
+// install that did not finish, e.g. because the controller was killed mid-update
+func (uc *UpdateController) repairInstall(ctx context.Context) {
+	// Repairing would reinstall the tracked branch; resumeUpdate reruns the rollback instead
+	if uc.state.Phase == PhaseRollingBack {
+		return
+	}
+
+	state, found, err := uc.steamClient.InstallState()
+	if err != nil {
+		klog.Warningf("Failed to read install state: %v", err)
+		return
+	}
+	if !found {
+		// Nothing on disk yet, the first check performs a full install
+		return
+	}
+
+	if state.FullyInstalled() {
+		klog.Infof("Install state: %s", state)
+		return
+	}
+
+	if uc.config.DryRun {
+		uc.dryRunSkip("repair the interrupted install (%s) with %s", state, uc.steamClient.DescribeUpdate(true))
+		return
+	}
+
+	klog.Warningf("Install was interrupted (%s), repairing before the first update check", state)
+	uc.controllerEvent(corev1.EventTypeWarning, EventInstallRepair, "Install was interrupted (%s), repairing", state)
+	if err := uc.steamClient.Repair(ctx); err != nil {
+		klog.Errorf("Install repair failed, the update check will retry it: %v", err)
+		uc.controllerEvent(corev1.EventTypeWarning, EventInstallRepairFailed, "Install repair failed: %v", err)
+		return
+	}
+
+	repaired, _, err := uc.steamClient.InstallState()
+	if err != nil {
+		klog.Warningf("Failed to read install state after repair: %v", err)
+		return
+	}
+	if !repaired.FullyInstalled() {
+		klog.Errorf("Install still incomplete after repair: %s", repaired)
+		return
+	}
+	klog.Infof("Install repaired: %s", repaired)
+	uc.controllerEvent(corev1.EventTypeNormal, EventInstallRepaired, "Install repaired: %s", repaired)
+}
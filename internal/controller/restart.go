@@ -2,75 +2,191 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
 )
 
-// restartPods restarts all pods matching the configured selector
-func (uc *UpdateController) restartPods(ctx context.Context) error {
-	klog.Infof("Finding pods with selector: %s", uc.config.PodSelector)
+// restartedAtAnnotation is the pod template annotation used to trigger rollouts, matching kubectl rollout restart
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
 
-	// Get pods matching selector
-	pods, err := uc.k8sClient.ListPodsBySelector(ctx, uc.config.PodSelector)
+// restartPods restarts the workloads of target pods that are still running a
+// build older than the last install
+func (uc *UpdateController) restartPods(ctx context.Context) (*RestartResult, error) {
+	workloads, excluded, err := uc.collectOutdatedWorkloads(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list pods: %w", err)
+		return nil, err
 	}
 
-	if len(pods) == 0 {
-		klog.Warning("No pods found matching selector")
-		return nil
+	if len(workloads) == 0 {
+		klog.Warning("No outdated pods found matching selector")
+		return &RestartResult{Excluded: excluded}, nil
 	}
 
-	klog.Infof("Found %d pods to restart", len(pods))
+	result := uc.restartWorkloads(ctx, workloads)
+	result.Excluded = excluded
+	return result, nil
+}
 
-	// Track workloads to restart (to avoid duplicate restarts)
-	workloadsRestarted := make(map[string]bool)
+// restartWorkloads plans and restarts the given workloads in priority order,
+// retrying failed ones with backoff
+func (uc *UpdateController) restartWorkloads(ctx context.Context, workloads []*workload) *RestartResult {
+	plans := uc.planRestarts(workloads)
+	klog.Infof("Restart plan for %d workloads:", len(plans))
+	for _, plan := range plans {
+		klog.Infof("  %s", plan)
+	}
 
-	for _, pod := range pods {
-		// Determine the owner (Deployment, StatefulSet, etc.)
-		ownerKind, ownerName, err := uc.k8sClient.GetPodOwner(pod)
-		if err != nil {
-			klog.Warningf("Failed to get owner for pod %s: %v", pod.Name, err)
+	result := &RestartResult{Plan: plans}
+	if uc.config.DryRun {
+		uc.recordPlan(&DryRunPlan{Trigger: "restart", Restart: plans})
+		return result
+	}
+	for _, plan := range plans {
+		switch plan.Policy {
+		case WorkloadPolicySkip:
+			klog.Infof("Skipping %s", plan.workload)
+			result.Skipped = append(result.Skipped, newWorkloadOutcome(plan, "policy skip"))
+			uc.reportRestart(plan, "skipped", result, nil)
+			continue
+		case WorkloadPolicyManual:
+			klog.Infof("%s requires a manual restart", plan.workload)
+			uc.workloadEvent(plan.workload, corev1.EventTypeNormal, EventRestartSkipped, "Running a build older than %s and requires a manual restart", uc.state.InstalledBuild)
+			result.Skipped = append(result.Skipped, newWorkloadOutcome(plan, "manual restart required"))
+			uc.reportRestart(plan, "skipped", result, nil)
 			continue
 		}
 
-		workloadKey := fmt.Sprintf("%s/%s", ownerKind, ownerName)
-		if workloadsRestarted[workloadKey] {
-			klog.V(2).Infof("Workload %s already restarted, skipping", workloadKey)
-			continue
+		uc.executePlan(ctx, plan, result)
+	}
+
+	uc.retryFailedRestarts(ctx, result)
+
+	klog.Infof("Restart finished: %d succeeded, %d failed, %d skipped", len(result.Succeeded), len(result.Failed), len(result.Skipped))
+	return result
+}
+
+// executePlan restarts a single planned workload and records the outcome
+func (uc *UpdateController) executePlan(ctx context.Context, plan *WorkloadPlan, result *RestartResult) {
+	w := plan.workload
+
+	klog.Infof("Restarting %s", w)
+	uc.workloadEvent(w, corev1.EventTypeNormal, EventRestartInitiated, "Restarting %d pods onto build %s with strategy %s", len(w.Pods), uc.state.InstalledBuild, plan.Strategy)
+	uc.reportRestart(plan, "started", result, nil)
+	if err := uc.restartWorkload(ctx, w); err != nil {
+		klog.Errorf("Failed to restart %s: %v", w, err)
+		uc.workloadEvent(w, corev1.EventTypeWarning, EventRestartFailed, "Restart failed: %v", err)
+		result.Failed = append(result.Failed, newWorkloadOutcome(plan, err.Error()))
+		uc.reportRestart(plan, "failed", result, err)
+		return
+	}
+
+	klog.Infof("Successfully initiated restart for %s", w)
+	uc.workloadEvent(w, corev1.EventTypeNormal, EventRestartCompleted, "Restarted onto build %s", uc.state.InstalledBuild)
+	result.Succeeded = append(result.Succeeded, newWorkloadOutcome(plan, ""))
+	uc.reportRestart(plan, "succeeded", result, nil)
+
+	if plan.MaxWait > 0 {
+		uc.waitForWorkloadReady(ctx, w, plan.MaxWait)
+	}
+}
+
+// retryFailedRestarts retries only the failed workloads, doubling the delay between attempts
+func (uc *UpdateController) retryFailedRestarts(ctx context.Context, result *RestartResult) {
+	delay := uc.config.RestartRetryBackoff
+
+	for attempt := 1; attempt <= uc.config.RestartRetries && len(result.Failed) > 0; attempt++ {
+		klog.Infof("Retrying %d failed restarts in %s (attempt %d/%d)", len(result.Failed), delay, attempt, uc.config.RestartRetries)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
 		}
 
-		klog.Infof("Restarting %s: %s", ownerKind, ownerName)
-		if err := uc.restartWorkload(ctx, ownerKind, ownerName); err != nil {
-			klog.Errorf("Failed to restart %s/%s: %v", ownerKind, ownerName, err)
-			continue
+		failed := result.Failed
+		result.Failed = nil
+		for _, outcome := range failed {
+			uc.executePlan(ctx, outcome.plan, result)
 		}
 
-		workloadsRestarted[workloadKey] = true
-		klog.Infof("Successfully initiated restart for %s/%s", ownerKind, ownerName)
+		delay *= 2
 	}
+}
 
-	if len(workloadsRestarted) == 0 {
-		return fmt.Errorf("failed to restart any workloads")
+// restartWorkload restarts a specific top-level workload
+func (uc *UpdateController) restartWorkload(ctx context.Context, w *workload) error {
+	switch uc.restartStrategy(w) {
+	case RestartStrategyPodDelete:
+		return uc.deletePodsOneByOne(ctx, w)
+	case RestartStrategyEvict:
+		return uc.evictPods(ctx, w)
+	case RestartStrategyRecreate:
+		return uc.recreateWorkload(ctx, w)
 	}
 
-	klog.Infof("Successfully restarted %d workloads", len(workloadsRestarted))
-	return nil
-}
+	switch w.groupKind().String() {
+	case "Deployment.apps":
+		return uc.k8sClientFor(w.Namespace).RestartDeployment(ctx, w.Name)
+	case "StatefulSet.apps":
+		return uc.k8sClientFor(w.Namespace).RestartStatefulSet(ctx, w.Name)
+	case "DaemonSet.apps":
+		return uc.k8sClientFor(w.Namespace).RestartDaemonSet(ctx, w.Name)
+	case "ReplicaSet.apps":
+		return uc.k8sClientFor(w.Namespace).RestartReplicaSet(ctx, w.Name)
+	case "Fleet.agones.dev":
+		// Agones never removes Allocated GameServers during a Fleet rollout
+		return uc.annotatePodTemplate(ctx, w)
+	case "GameServer.agones.dev":
+		return uc.restartGameServer(ctx, w.Namespace, w.Name)
+	}
 
-// restartWorkload restarts a specific workload by kind and name
-func (uc *UpdateController) restartWorkload(ctx context.Context, kind, name string) error {
-	switch kind {
-	case "Deployment":
-		return uc.k8sClient.RestartDeployment(ctx, name)
-	case "StatefulSet":
-		return uc.k8sClient.RestartStatefulSet(ctx, name)
-	case "DaemonSet":
-		return uc.k8sClient.RestartDaemonSet(ctx, name)
-	case "ReplicaSet":
-		return uc.k8sClient.RestartReplicaSet(ctx, name)
+	action, ok := uc.kindAction(w)
+	if !ok {
+		return fmt.Errorf("unsupported workload kind: %s", w.groupKind())
+	}
+
+	switch action {
+	case KindActionAnnotate:
+		return uc.annotatePodTemplate(ctx, w)
+	case KindActionDeletePods:
+		return uc.deletePodsOneByOne(ctx, w)
 	default:
-		return fmt.Errorf("unsupported workload kind: %s", kind)
+		return fmt.Errorf("unsupported action %q for kind %s", action, w.groupKind())
+	}
+}
+
+// annotatePodTemplate bumps the restart annotation on a workload's pod template through the dynamic client
+func (uc *UpdateController) annotatePodTemplate(ctx context.Context, w *workload) error {
+	if w.Resource.Empty() {
+		return fmt.Errorf("no API resource known for kind %s", w.groupKind())
 	}
+
+	patch, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build restart patch: %w", err)
+	}
+
+	_, err = uc.dynamicClient.Resource(w.Resource).Namespace(w.Namespace).Patch(ctx, w.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to patch %s: %w", w.Resource.Resource, err)
+	}
+
+	klog.V(2).Infof("Added restart annotation to %s", w)
+	return nil
 }
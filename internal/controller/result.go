@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UpdateResult summarises a single pass through applyUpdate
+type UpdateResult struct {
+	StartedAt   time.Time      `json:"startedAt"`
+	FinishedAt  time.Time      `json:"finishedAt"`
+	Success     bool           `json:"success"`
+	Error       string         `json:"error,omitempty"`
+	Restart     *RestartResult `json:"restart,omitempty"`
+	LogFindings []LogFinding   `json:"logFindings,omitempty"`
+
+	// RolledBackTo is the build installed by an automatic rollback after log scan failures
+	RolledBackTo string `json:"rolledBackTo,omitempty"`
+}
+
+// PartialRestartPolicy decides whether a restart with some failed workloads counts as success
+type PartialRestartPolicy string
+
+const (
+	// PartialRestartAll requires every workload to restart
+	PartialRestartAll PartialRestartPolicy = "all"
+	// PartialRestartAny accepts the restart if at least one workload restarted
+	PartialRestartAny PartialRestartPolicy = "any"
+)
+
+// WorkloadOutcome records what happened to one workload during a restart
+type WorkloadOutcome struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason,omitempty"`
+
+	plan *WorkloadPlan
+}
+
+func newWorkloadOutcome(plan *WorkloadPlan, reason string) WorkloadOutcome {
+	return WorkloadOutcome{
+		Namespace: plan.Namespace,
+		Kind:      plan.Kind,
+		Name:      plan.Name,
+		Reason:    reason,
+		plan:      plan,
+	}
+}
+
+func (o WorkloadOutcome) String() string {
+	if o.Reason == "" {
+		return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+	}
+	return fmt.Sprintf("%s %s/%s: %s", o.Kind, o.Namespace, o.Name, o.Reason)
+}
+
+// RestartResult lists the outcome of every planned workload
+type RestartResult struct {
+	Plan      []*WorkloadPlan   `json:"plan"`
+	Succeeded []WorkloadOutcome `json:"succeeded"`
+	Failed    []WorkloadOutcome `json:"failed"`
+	Skipped   []WorkloadOutcome `json:"skipped"`
+	Excluded  []ExcludedPod     `json:"excluded,omitempty"`
+}
+
+// err applies the partial restart policy to the result
+func (r *RestartResult) err(policy PartialRestartPolicy) error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+
+	if policy == PartialRestartAny && len(r.Succeeded) > 0 {
+		return nil
+	}
+
+	failed := outcomeStrings(r.Failed)
+	return fmt.Errorf("%d of %d workloads failed to restart: %s", len(r.Failed), len(r.Failed)+len(r.Succeeded), strings.Join(failed, "; "))
+}
+
+// restartSummary describes the restart outcome for events
+func (r *UpdateResult) restartSummary() string {
+	if r.Restart == nil {
+		return "no workloads restarted"
+	}
+	return fmt.Sprintf("%d workloads restarted, %d failed, %d skipped", len(r.Restart.Succeeded), len(r.Restart.Failed), len(r.Restart.Skipped))
+}
+
+// logFailures returns the log findings classified as failures
+func (r *UpdateResult) logFailures() []LogFinding {
+	var failures []LogFinding
+	for _, finding := range r.LogFindings {
+		if finding.Severity == LogSeverityFailure {
+			failures = append(failures, finding)
+		}
+	}
+	return failures
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/UDL-TF/UpdateController/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// ErrNoRollbackBranch is returned when a rollback is requested without ROLLBACK_BRANCH
+var ErrNoRollbackBranch = errors.New("no rollback branch configured")
+
+// rollback installs the build of ROLLBACK_BRANCH, restarts the target
+// workloads onto it and pauses automatic updates so the next check does not
+// reinstall the build that was rolled back. Resuming updates clears the pause
+func (uc *UpdateController) rollback(ctx context.Context, reason string) (err error) {
+	branch := uc.config.RollbackBranch
+	if branch == "" {
+		return ErrNoRollbackBranch
+	}
+
+	if uc.config.DryRun {
+		selection, err := uc.selectTargetPods(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list pods: %w", err)
+		}
+		uc.recordPlan(&DryRunPlan{
+			Trigger:  "rollback",
+			Steps:    []string{uc.steamClient.DescribeRollback(branch)},
+			Recovery: []string{"automatic updates are paused until resumed"},
+			Restart:  uc.planRestarts(uc.groupWorkloads(ctx, selection.Pods)),
+			Excluded: selection.Excluded,
+		})
+		return nil
+	}
+
+	// A resumed rollback already recorded the build it replaces
+	if uc.state.Phase != PhaseRollingBack {
+		uc.state.RolledBackFrom = uc.state.InstalledBuild
+	}
+	klog.Warningf("Rolling back from build %s to branch %s: %s", uc.state.RolledBackFrom, branch, reason)
+	uc.controllerEvent(corev1.EventTypeWarning, EventRollbackStarted, "Rolling back from build %s to branch %s: %s", uc.state.RolledBackFrom, branch, reason)
+
+	// Pausing first keeps the next check from undoing an interrupted rollback
+	uc.state.Paused = true
+	uc.setPhase(PhaseRollingBack)
+	metrics.UpdateInProgress.With(uc.metricLabels()).Set(1)
+	defer func() {
+		metrics.UpdateInProgress.With(uc.metricLabels()).Set(0)
+		// A shutdown keeps the saved phase so the next start resumes the rollback
+		if ctx.Err() != nil {
+			return
+		}
+		uc.setPhase(PhaseIdle)
+		if err != nil {
+			uc.controllerEvent(corev1.EventTypeWarning, EventRollbackFailed, "Rollback to branch %s failed: %v", branch, err)
+		}
+	}()
+
+	if err := uc.steamClient.Rollback(ctx, branch); err != nil {
+		return err
+	}
+	uc.recordInstall(time.Now())
+
+	uc.state.RestartedAt = time.Now()
+	restart, err := uc.restartPods(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to restart pods after rollback: %w", err)
+	}
+	uc.state.Restarted = outcomeStrings(restart.Succeeded)
+	if err := restart.err(uc.config.PartialRestartPolicy); err != nil {
+		return err
+	}
+
+	klog.Infof("Rolled back to build %s from branch %s, automatic updates paused", uc.state.InstalledBuild, branch)
+	uc.controllerEvent(corev1.EventTypeNormal, EventRollbackSucceeded, "Rolled back from build %s to build %s, automatic updates paused", uc.state.RolledBackFrom, uc.state.InstalledBuild)
+	return nil
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// UpdatePhase is the stage an update has reached
+type UpdatePhase string
+
+const (
+	// PhaseIdle means no update is in progress
+	PhaseIdle UpdatePhase = "idle"
+	// PhaseDownloading runs steamcmd app_update
+	PhaseDownloading UpdatePhase = "downloading"
+	// PhaseValidating runs steamcmd validate on the new install
+	PhaseValidating UpdatePhase = "validating"
+	// PhaseRestarting restarts workloads still running the old build
+	PhaseRestarting UpdatePhase = "restarting"
+	// PhaseVerifying scans the logs of restarted pods
+	PhaseVerifying UpdatePhase = "verifying"
+	// PhaseRollingBack installs the rollback branch and restarts workloads onto it
+	PhaseRollingBack UpdatePhase = "rolling-back"
+)
+
+// updateState is the controller state that survives a controller restart
+type updateState struct {
+	Phase          UpdatePhase `json:"phase"`
+	TargetBuild    string      `json:"targetBuild,omitempty"`
+	RetryCount     int         `json:"retryCount"`
+	Paused         bool        `json:"paused,omitempty"`
+	StartedAt      time.Time   `json:"startedAt,omitzero"`
+	PhaseStartedAt time.Time   `json:"phaseStartedAt,omitzero"`
+
+	// InstalledBuild and InstalledAt record the last successful install
+	InstalledBuild string    `json:"installedBuild,omitempty"`
+	InstalledAt    time.Time `json:"installedAt,omitzero"`
+	// InstallSeeded marks a record taken from the manifest instead of an
+	// install the controller ran; outdated pods are then only reported
+	InstallSeeded bool `json:"installSeeded,omitempty"`
+
+	// RestartedAt and Restarted record the last restart and the workloads it restarted
+	RestartedAt time.Time `json:"restartedAt,omitzero"`
+	Restarted   []string  `json:"restarted,omitempty"`
+
+	// RolledBackFrom is the build the last rollback replaced; automatic updates
+	// stay paused until it is cleared by resuming them
+	RolledBackFrom string `json:"rolledBackFrom,omitempty"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// statePath returns where state is persisted; relative paths are on the game volume
+func (uc *UpdateController) statePath() string {
+	if filepath.IsAbs(uc.config.StateFile) {
+		return uc.config.StateFile
+	}
+	return filepath.Join(uc.config.GameMountPath, uc.config.StateFile)
+}
+
+// loadState restores state saved by a previous run, if any
+func (uc *UpdateController) loadState() {
+	path := uc.statePath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		klog.Warningf("Failed to read state file %s: %v", path, err)
+		return
+	}
+
+	var state updateState
+	if err := json.Unmarshal(data, &state); err != nil {
+		klog.Warningf("Ignoring corrupt state file %s: %v", path, err)
+		return
+	}
+
+	uc.state = state
+	klog.Infof("Loaded state from %s: phase %s, installed build %s, %d retries", path, state.Phase, state.InstalledBuild, state.RetryCount)
+}
+
+// saveState writes state atomically so a crash never leaves a partial file.
+// Dry-run mode keeps state in memory only
+func (uc *UpdateController) saveState() {
+	if uc.config.DryRun {
+		return
+	}
+	if err := uc.writeState(); err != nil {
+		klog.Warningf("Failed to persist state: %v", err)
+	}
+}
+
+func (uc *UpdateController) writeState() error {
+	uc.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(uc.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := uc.statePath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// setPhase moves the update to a new phase and persists it
+func (uc *UpdateController) setPhase(phase UpdatePhase) {
+	uc.state.Phase = phase
+	uc.state.PhaseStartedAt = time.Now()
+	uc.saveState()
+	uc.publishStatus()
+	uc.progress.publish(uc.progressEvent(ProgressPhase))
+	klog.V(2).Infof("Update phase: %s", phase)
+}
+
+// resumeUpdate continues an update the previous run was interrupted in,
+// starting again from the stage that had not completed. When gated, it waits
+// while updates are paused or outside every maintenance window
+func (uc *UpdateController) resumeUpdate(ctx context.Context, gated bool) {
+	phase := uc.state.Phase
+	if phase == "" || phase == PhaseIdle {
+		return
+	}
+
+	if uc.config.DryRun {
+		uc.dryRunSkip("resume the update to build %s interrupted while %s", uc.state.TargetBuild, phase)
+		return
+	}
+
+	// Every resumed phase goes on to restart pods, so it waits for updates to be
+	// resumed and for a maintenance window like a new update; a rollback pauses
+	// updates itself and only waits for the window
+	var waitFor string
+	switch {
+	case !gated:
+	case phase != PhaseRollingBack && uc.state.Paused:
+		waitFor = "automatic updates to be resumed"
+	case !uc.inMaintenanceWindow(time.Now()):
+		waitFor = "the next maintenance window"
+	}
+	uc.resumePending = waitFor != ""
+	if uc.resumePending {
+		klog.Infof("Update to build %s was interrupted while %s, waiting for %s to continue", uc.state.TargetBuild, phase, waitFor)
+		return
+	}
+
+	if phase == PhaseRollingBack {
+		klog.Infof("Resuming rollback from build %s", uc.state.RolledBackFrom)
+		if err := uc.rollback(ctx, "resuming an interrupted rollback"); err != nil {
+			klog.Errorf("Resumed rollback failed: %v", err)
+		}
+		return
+	}
+
+	klog.Infof("Resuming update to build %s interrupted while %s (started %s)",
+		uc.state.TargetBuild, phase, uc.state.StartedAt.Format(time.RFC3339))
+	if err := uc.runUpdate(ctx, phase); err != nil {
+		klog.Errorf("Resumed update failed: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+// ExcludedPod is a pod matching the selectors that was not treated as a target
+type ExcludedPod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+// podSelection is the result of listing target pods
+type podSelection struct {
+	Pods     []*corev1.Pod
+	Excluded []ExcludedPod
+}
+
+// listTargetPods returns the running pods the controller is responsible for across all target namespaces
+func (uc *UpdateController) listTargetPods(ctx context.Context) ([]*corev1.Pod, error) {
+	selection, err := uc.selectTargetPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return selection.Pods, nil
+}
+
+// selectTargetPods lists pods matching the label and field selectors in every
+// target namespace and filters out pods that cannot meaningfully be restarted
+func (uc *UpdateController) selectTargetPods(ctx context.Context) (*podSelection, error) {
+	if _, err := fields.ParseSelector(uc.config.FieldSelector); err != nil {
+		return nil, fmt.Errorf("invalid field selector %q: %w", uc.config.FieldSelector, err)
+	}
+
+	namespaces, err := uc.targetNamespaces(ctx)
+	if err != nil && len(namespaces) == 0 {
+		return nil, err
+	}
+	if err != nil {
+		klog.Warningf("Using static namespaces only: %v", err)
+	}
+
+	selection := &podSelection{}
+	for _, ns := range namespaces {
+		selector := uc.podSelectorFor(ns)
+		if _, err := labels.Parse(selector); err != nil {
+			return nil, fmt.Errorf("invalid pod selector %q for namespace %s: %w", selector, ns, err)
+		}
+
+		podList, err := uc.clientset.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+			FieldSelector: uc.config.FieldSelector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", ns, err)
+		}
+
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if reason := exclusionReason(pod); reason != "" {
+				klog.V(2).Infof("Excluding pod %s/%s: %s", pod.Namespace, pod.Name, reason)
+				selection.Excluded = append(selection.Excluded, ExcludedPod{Namespace: pod.Namespace, Name: pod.Name, Reason: reason})
+				continue
+			}
+			selection.Pods = append(selection.Pods, pod)
+		}
+	}
+
+	if len(selection.Excluded) > 0 {
+		klog.Infof("Excluded %d pods: %s", len(selection.Excluded), summarizeExclusions(selection.Excluded))
+	}
+
+	return selection, nil
+}
+
+// exclusionReason explains why a pod is not a restart target, or returns "" if it is
+func exclusionReason(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "terminating"
+	}
+
+	// Pods of completed Jobs end up Succeeded or Failed; running Job pods stay targets
+	if pod.Status.Phase != corev1.PodRunning {
+		return "phase " + string(pod.Status.Phase)
+	}
+
+	return ""
+}
+
+// summarizeExclusions counts exclusions by reason category, e.g. "2 phase Pending, 1 terminating"
+func summarizeExclusions(excluded []ExcludedPod) string {
+	counts := make(map[string]int)
+	for _, pod := range excluded {
+		counts[pod.Reason]++
+	}
+
+	parts := make([]string, 0, len(counts))
+	for reason, count := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// TargetWorkload is a targeted workload and the build its pods run, published for the dashboard
+type TargetWorkload struct {
+	Namespace string      `json:"namespace"`
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	Pods      []TargetPod `json:"pods"`
+}
+
+// TargetPod is one target pod; Build is only known for pods started after the last install
+type TargetPod struct {
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"startedAt,omitzero"`
+	Build     string    `json:"build,omitempty"`
+	Outdated  bool      `json:"outdated"`
+}
+
+// refreshTargets snapshots the target workloads and their pods' builds for the status
+func (uc *UpdateController) refreshTargets(ctx context.Context) {
+	selection, err := uc.selectTargetPods(ctx)
+	if err != nil {
+		klog.V(2).Infof("Failed to list targets for status: %v", err)
+		return
+	}
+
+	workloads := uc.groupWorkloads(ctx, selection.Pods)
+	targets := make([]TargetWorkload, 0, len(workloads))
+	for _, w := range workloads {
+		target := TargetWorkload{Namespace: w.Namespace, Kind: w.Kind, Name: w.Name}
+		for _, pod := range w.Pods {
+			tp := TargetPod{Name: pod.Name, StartedAt: podStartedAt(pod), Outdated: uc.isPodOutdated(pod)}
+			if !tp.Outdated {
+				tp.Build = uc.state.InstalledBuild
+			}
+			target.Pods = append(target.Pods, tp)
+		}
+		targets = append(targets, target)
+	}
+
+	uc.targets = targets
+}
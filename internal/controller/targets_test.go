@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExclusionReason(t *testing.T) {
+	now := metav1.Now()
+	jobOwner := []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: "map-sync"}}
+
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want string
+	}{
+		{
+			name: "running",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			want: "",
+		},
+		{
+			name: "running Job pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: jobOwner},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			want: "",
+		},
+		{
+			name: "completed Job pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: jobOwner},
+				Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			want: "phase Succeeded",
+		},
+		{
+			name: "failed",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed}},
+			want: "phase Failed",
+		},
+		{
+			name: "pending",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}},
+			want: "phase Pending",
+		},
+		{
+			name: "terminating",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			want: "terminating",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exclusionReason(&tt.pod); got != tt.want {
+				t.Errorf("exclusionReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeExclusions(t *testing.T) {
+	excluded := []ExcludedPod{
+		{Name: "a", Reason: "phase Pending"},
+		{Name: "b", Reason: "terminating"},
+		{Name: "c", Reason: "phase Pending"},
+	}
+
+	if got, want := summarizeExclusions(excluded), "1 terminating, 2 phase Pending"; got != want {
+		t.Errorf("summarizeExclusions() = %q, want %q", got, want)
+	}
+}
@@ -3,109 +3,439 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/UDL-TF/RestartController/pkg/k8s"
 
+	"github.com/UDL-TF/UpdateController/internal/metrics"
 	"github.com/UDL-TF/UpdateController/internal/steamcmd"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
 // UpdateController manages TF2 server updates and pod restarts
 type UpdateController struct {
-	config      *Config
-	k8sClient   *k8s.Client
-	steamClient *steamcmd.Client
-	retryCount  int
+	config        *Config
+	clientset     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+	restMapper    meta.ResettableRESTMapper
+	steamClient   *steamcmd.Client
+	lastResult    *UpdateResult
+
+	// history holds the most recent update results, newest first
+	history []*UpdateResult
+
+	// plan is the last dry-run plan; pendingRecovery are startup recovery steps dry-run skipped
+	plan            *DryRunPlan
+	pendingRecovery []string
+
+	// targets is the workload snapshot taken after the last check or command
+	targets []TargetWorkload
+
+	// state is persisted to the state file so an interrupted update can resume
+	state updateState
+
+	// heartbeat tracks the main loop and steamcmd runs for the liveness probe
+	heartbeat *heartbeat
+
+	// recorder emits Events on workloads and on eventTarget, the controller-owned object
+	recorder    record.EventRecorder
+	eventTarget *corev1.ObjectReference
+
+	// updateDeferred is set when the last check found an update it did not apply
+	// because it was outside every maintenance window or updates are paused
+	updateDeferred bool
+	lastCheck      time.Time
+	lastCheckErr   error
+
+	// resumePending is set while an interrupted update waits for updates to be
+	// resumed or for a maintenance window before it continues
+	resumePending bool
+
+	// seedReported is set once the pods older than a seeded install were logged
+	seedReported bool
+
+	// commands are manual operations from the admin API, run on the main loop
+	commands    chan commandRequest
+	lastCommand *CommandResult
+
+	// verifications delivers post-restart log scans run off the main loop; it
+	// is only set by Run, so other callers scan in line
+	verifications chan *verification
+
+	// progress streams phase, download and restart progress to the admin API
+	progress  *progressHub
+	downloads downloadTracker
+
+	// reloads holds a reloaded config until the main loop is between cycles;
+	// configMu guards swapping config against readers off the main loop
+	reloads  chan *Config
+	configMu sync.RWMutex
+
+	// status is the snapshot served to the admin API
+	status   Status
+	statusMu sync.RWMutex
+
+	// k8sClients holds a RestartController client per target namespace
+	k8sClients   map[string]*k8s.Client
+	k8sClientsMu sync.Mutex
 }
 
 // NewUpdateController creates a new UpdateController instance
-func NewUpdateController(config *Config, k8sClient *k8s.Client, steamClient *steamcmd.Client) *UpdateController {
-	return &UpdateController{
-		config:      config,
-		k8sClient:   k8sClient,
-		steamClient: steamClient,
-		retryCount:  0,
+func NewUpdateController(config *Config, clientset *kubernetes.Clientset, dynamicClient dynamic.Interface, steamClient *steamcmd.Client) *UpdateController {
+	uc := &UpdateController{
+		config:        config,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		restMapper:    restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery())),
+		steamClient:   steamClient,
+		state:         updateState{Phase: PhaseIdle},
+		k8sClients:    make(map[string]*k8s.Client),
+		heartbeat:     &heartbeat{},
+		commands:      make(chan commandRequest, commandQueueSize),
+		progress:      newProgressHub(),
+		reloads:       make(chan *Config, 1),
 	}
+	uc.publishStatus()
+
+	steamClient.SetTimeout(config.SteamCMDTimeout)
+	steamClient.SetHooks(steamcmd.Hooks{
+		StageStarted: func(stage string, deadline time.Time) {
+			uc.heartbeat.startStage(stage, deadline)
+			uc.downloads.reset(stage)
+		},
+		StageFinished: func(stage string, duration time.Duration, err error) {
+			uc.heartbeat.endStage()
+			uc.observeStage(stage, duration, err)
+		},
+		Recovery: func(message string) {
+			metrics.StateRecoveries.With(uc.metricLabels()).Inc()
+			uc.controllerEvent(corev1.EventTypeWarning, EventStateRecovery, "%s", message)
+		},
+		Progress: uc.reportDownload,
+	})
+
+	return uc
 }
 
 // Run starts the controller's main loop
 func (uc *UpdateController) Run(ctx context.Context) error {
 	klog.Info("UpdateController started")
+	if uc.config.DryRun {
+		klog.Info("Dry-run mode: steamcmd updates and Kubernetes changes are only planned")
+	}
 
 	ticker := time.NewTicker(uc.config.CheckInterval)
 	defer ticker.Stop()
 
+	broadcaster := newEventBroadcaster(uc.clientset)
+	defer broadcaster.Shutdown()
+	uc.recorder = newEventRecorder(broadcaster)
+	uc.eventTarget = controllerPodReference(ctx, uc.clientset)
+
+	uc.verifications = make(chan *verification, 1)
+	uc.heartbeat.work()
+	uc.start(ctx, true)
+
+	// A nil channel never fires, so the watcher case is inert when disabled
+	var logTrigger <-chan struct{}
+	if uc.config.LogTriggerEnabled {
+		watcher := newLogWatcher(uc)
+		go watcher.run(ctx)
+		logTrigger = watcher.trigger
+	}
+
 	// Perform initial check
 	if err := uc.performUpdateCheck(ctx); err != nil {
 		klog.Errorf("Initial update check failed: %v", err)
 	}
 
 	for {
+		uc.heartbeat.idle()
 		select {
 		case <-ctx.Done():
 			klog.Info("UpdateController stopping")
 			return ctx.Err()
 		case <-ticker.C:
+			uc.heartbeat.work()
 			if err := uc.performUpdateCheck(ctx); err != nil {
 				klog.Errorf("Update check failed: %v", err)
 			}
+		case <-logTrigger:
+			uc.heartbeat.work()
+			if err := uc.performUpdateCheck(ctx); err != nil {
+				klog.Errorf("Log-triggered update check failed: %v", err)
+			}
+		case req := <-uc.commands:
+			uc.heartbeat.work()
+			uc.runCommand(ctx, req)
+		case v := <-uc.verifications:
+			uc.heartbeat.work()
+			uc.finishVerification(ctx, v)
+		case config := <-uc.reloads:
+			uc.heartbeat.work()
+			interval := uc.config.CheckInterval
+			uc.applyConfig(config)
+			// Resetting an unchanged interval would push back the next check
+			if config.CheckInterval != interval {
+				ticker.Reset(config.CheckInterval)
+			}
 		}
 	}
 }
 
+// start prepares the controller before its first update check; gated holds
+// an interrupted update back while automatic updates may not run
+func (uc *UpdateController) start(ctx context.Context, gated bool) {
+	uc.checkPermissions(ctx)
+
+	// Finish any recreate a previous run was interrupted in the middle of
+	uc.resumeRecreates(ctx)
+	uc.loadState()
+	uc.repairInstall(ctx)
+	uc.seedInstall()
+	uc.resumeUpdate(ctx, gated)
+}
+
 // performUpdateCheck checks for updates and applies them if available
 func (uc *UpdateController) performUpdateCheck(ctx context.Context) error {
 	klog.Info("Checking for TF2 updates...")
+	defer uc.publishStatus()
+	defer uc.refreshTargets(ctx)
+
+	// An interrupted update held back at startup continues once it is allowed to
+	if uc.resumePending {
+		uc.resumeUpdate(ctx, true)
+	}
 
 	// Check if update is available
 	updateAvailable, err := uc.steamClient.CheckUpdate(ctx)
+	uc.recordCheckMetrics(err)
+	uc.lastCheck, uc.lastCheckErr = time.Now(), err
 	if err != nil {
 		return fmt.Errorf("failed to check for updates: %w", err)
 	}
 
+	uc.updateDeferred = false
+	if uc.state.Phase != PhaseIdle {
+		if updateAvailable {
+			klog.Infof("Update available, waiting for the update to build %s to finish %s", uc.state.TargetBuild, uc.state.Phase)
+			uc.updateDeferred = true
+		}
+		return nil
+	}
+	if uc.state.Paused {
+		if updateAvailable {
+			klog.Info("Update available, but automatic updates are paused")
+			uc.updateDeferred = true
+		}
+		return nil
+	}
+
+	if !uc.inMaintenanceWindow(time.Now()) {
+		if updateAvailable {
+			klog.Info("Update available, deferring until the next maintenance window")
+			uc.controllerEvent(corev1.EventTypeNormal, EventUpdateDeferred, "Build %s is available, waiting for a maintenance window", uc.steamClient.LatestBuild())
+			uc.updateDeferred = true
+		}
+		return nil
+	}
+
 	if !updateAvailable {
 		klog.Info("No updates available, continuing monitoring")
+		uc.reconcileOutdated(ctx)
 		return nil
 	}
 
 	klog.Info("Update available! Starting update process...")
+	uc.controllerEvent(corev1.EventTypeNormal, EventUpdateDetected, "Build %s is available, installed build is %q", uc.steamClient.LatestBuild(), uc.state.InstalledBuild)
 	return uc.applyUpdate(ctx)
 }
 
 // applyUpdate downloads and applies the update, then restarts pods
 func (uc *UpdateController) applyUpdate(ctx context.Context) error {
-	// Download and install update
-	klog.Info("Downloading and installing update...")
-	if err := uc.steamClient.ApplyUpdate(ctx); err != nil {
-		return uc.handleUpdateFailure(err)
+	if uc.config.DryRun {
+		return uc.planUpdate(ctx)
+	}
+
+	uc.state.TargetBuild = uc.steamClient.LatestBuild()
+	uc.state.StartedAt = time.Now()
+	uc.state.Restarted = nil
+	return uc.runUpdate(ctx, PhaseDownloading)
+}
+
+// runUpdate runs the update stages beginning at from, persisting each stage
+// as it starts so a restarted controller can pick up where it left off
+func (uc *UpdateController) runUpdate(ctx context.Context, from UpdatePhase) (err error) {
+	result := &UpdateResult{StartedAt: time.Now()}
+	failureClass := "download"
+	verifying := false
+	metrics.UpdateInProgress.With(uc.metricLabels()).Set(1)
+	defer func() {
+		// A shutdown mid-update keeps the saved phase so the next start resumes it
+		if ctx.Err() != nil {
+			metrics.UpdateInProgress.With(uc.metricLabels()).Set(0)
+			klog.Infof("Update to build %s interrupted while %s, resuming on the next start", uc.state.TargetBuild, uc.state.Phase)
+			return
+		}
+		// The main loop finishes the update once the log scan reports back
+		if verifying {
+			return
+		}
+		uc.finishUpdate(ctx, result, failureClass, err)
+	}()
+
+	switch from {
+	case PhaseDownloading:
+		// Download and install update
+		uc.setPhase(PhaseDownloading)
+		klog.Info("Downloading and installing update...")
+		uc.controllerEvent(corev1.EventTypeNormal, EventDownloadStarted, "Downloading build %s", uc.state.TargetBuild)
+		if err := uc.steamClient.ApplyUpdate(ctx); err != nil {
+			uc.controllerEvent(corev1.EventTypeWarning, EventDownloadFailed, "Download failed: %v", err)
+			return uc.handleUpdateFailure(err)
+		}
+		uc.controllerEvent(corev1.EventTypeNormal, EventDownloadFinished, "Downloaded build %s", uc.state.TargetBuild)
+		if install, _, err := uc.steamClient.InstallState(); err == nil {
+			metrics.BytesDownloaded.With(uc.metricLabels()).Add(float64(install.BytesDownloaded))
+		}
+		fallthrough
+
+	case PhaseValidating:
+		// Validate update
+		uc.setPhase(PhaseValidating)
+		failureClass = "validation"
+		klog.Info("Validating update...")
+		if err := uc.steamClient.ValidateUpdate(ctx); err != nil {
+			uc.controllerEvent(corev1.EventTypeWarning, EventValidationFailed, "Validation failed: %v", err)
+			return uc.handleUpdateFailure(fmt.Errorf("update validation failed: %w", err))
+		}
+		uc.controllerEvent(corev1.EventTypeNormal, EventValidationSucceeded, "Validated the installed files")
+
+		uc.recordInstall(time.Now())
+
+		// Failed restarts are retried on their own, so the update itself is done
+		uc.state.RetryCount = 0
+		fallthrough
+
+	case PhaseRestarting:
+		// Restart affected pods; a resumed restart keeps its start time so the
+		// log scan still covers pods restarted before the interruption
+		if from != PhaseRestarting || uc.state.RestartedAt.Before(uc.state.StartedAt) {
+			uc.state.RestartedAt = time.Now()
+		}
+		uc.setPhase(PhaseRestarting)
+		failureClass = "restart"
+		klog.Info("Update successful! Restarting affected pods...")
+		restart, err := uc.restartPods(ctx)
+		result.Restart = restart
+		if err != nil {
+			return fmt.Errorf("failed to restart pods: %w", err)
+		}
+		uc.state.Restarted = outcomeStrings(restart.Succeeded)
+		if err := restart.err(uc.config.PartialRestartPolicy); err != nil {
+			return err
+		}
+		if len(restart.Failed) > 0 {
+			klog.Warningf("%d workloads failed to restart, accepted by partial restart policy %q", len(restart.Failed), uc.config.PartialRestartPolicy)
+		}
+		fallthrough
+
+	case PhaseVerifying:
+		uc.setPhase(PhaseVerifying)
+		failureClass = "log_scan"
+		if uc.verifications != nil && uc.config.LogScanWindow > 0 {
+			verifying = true
+			go uc.scanAsync(ctx, result, uc.state.RestartedAt)
+			return nil
+		}
+		return uc.verify(ctx, result, uc.scanRestartedPods(ctx, uc.state.RestartedAt))
+
+	default:
+		return fmt.Errorf("cannot run update from phase %q", from)
+	}
+}
+
+// verification is the outcome of a post-restart log scan for an update
+type verification struct {
+	result   *UpdateResult
+	findings []LogFinding
+}
+
+// scanAsync scans the restarted pods' logs off the main loop, which keeps
+// serving commands, reloads and log triggers for the whole scan window
+func (uc *UpdateController) scanAsync(ctx context.Context, result *UpdateResult, restartedAt time.Time) {
+	findings := uc.scanRestartedPods(ctx, restartedAt)
+	select {
+	case uc.verifications <- &verification{result: result, findings: findings}:
+	case <-ctx.Done():
+	}
+}
+
+// finishVerification completes an update whose log scan ran off the main loop
+func (uc *UpdateController) finishVerification(ctx context.Context, v *verification) {
+	err := uc.verify(ctx, v.result, v.findings)
+	uc.finishUpdate(ctx, v.result, "log_scan", err)
+}
+
+// verify fails the update when the log scan found failures, rolling back if
+// configured. Broken plugins fail the update without sending it back through steamcmd
+func (uc *UpdateController) verify(ctx context.Context, result *UpdateResult, findings []LogFinding) error {
+	result.LogFindings = findings
+	failures := result.logFailures()
+	if len(failures) == 0 {
+		return nil
 	}
 
-	// Validate update
-	klog.Info("Validating update...")
-	if err := uc.steamClient.ValidateUpdate(ctx); err != nil {
-		return uc.handleUpdateFailure(fmt.Errorf("update validation failed: %w", err))
+	err := fmt.Errorf("post-restart log scan found %d failure(s), first: %s", len(failures), failures[0])
+	if uc.config.RollbackOnFailure {
+		if rollbackErr := uc.rollback(ctx, err.Error()); rollbackErr != nil {
+			klog.Errorf("Automatic rollback failed: %v", rollbackErr)
+		} else {
+			result.RolledBackTo = uc.state.InstalledBuild
+		}
 	}
+	return err
+}
+
+// finishUpdate records the outcome of an update and returns to idle
+func (uc *UpdateController) finishUpdate(ctx context.Context, result *UpdateResult, failureClass string, err error) {
+	metrics.UpdateInProgress.With(uc.metricLabels()).Set(0)
 
-	// Restart affected pods
-	klog.Info("Update successful! Restarting affected pods...")
-	if err := uc.restartPods(ctx); err != nil {
-		return uc.handleUpdateFailure(fmt.Errorf("failed to restart pods: %w", err))
+	result.FinishedAt = time.Now()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
 	}
+	uc.lastResult = result
+	uc.recordHistory(result)
+	uc.setPhase(PhaseIdle)
+	uc.notify(ctx, result)
+	uc.recordUpdateMetrics(result, failureClass)
 
-	klog.Info("Update process completed successfully")
-	uc.retryCount = 0
-	return nil
+	if err != nil {
+		uc.controllerEvent(corev1.EventTypeWarning, EventUpdateFailed, "Update to build %s failed: %v", uc.state.TargetBuild, err)
+	} else {
+		klog.Info("Update process completed successfully")
+		uc.controllerEvent(corev1.EventTypeNormal, EventUpdateSucceeded, "Build %s installed and %s", uc.state.InstalledBuild, result.restartSummary())
+	}
 }
 
 // handleUpdateFailure handles update failures with retry logic
 func (uc *UpdateController) handleUpdateFailure(err error) error {
-	uc.retryCount++
-	klog.Errorf("Update failed (attempt %d/%d): %v", uc.retryCount, uc.config.MaxRetries, err)
+	uc.state.RetryCount++
+	klog.Errorf("Update failed (attempt %d/%d): %v", uc.state.RetryCount, uc.config.MaxRetries, err)
 
-	if uc.retryCount >= uc.config.MaxRetries {
+	if uc.state.RetryCount >= uc.config.MaxRetries {
 		klog.Errorf("Max retries exceeded, giving up on this update")
-		uc.retryCount = 0
+		uc.state.RetryCount = 0
 		return fmt.Errorf("update failed after %d attempts: %w", uc.config.MaxRetries, err)
 	}
 
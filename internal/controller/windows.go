@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// MaintenanceWindow is a recurring period of the day in which updates and restarts may run.
+// A window whose end is not after its start runs past midnight into the next day
+type MaintenanceWindow struct {
+	// Days limits the window to the days it starts on, e.g. "Mon"; empty means every day
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	TimeZone string   `json:"timeZone,omitempty"`
+}
+
+func (w MaintenanceWindow) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	zone := w.TimeZone
+	if zone == "" {
+		zone = "UTC"
+	}
+	return fmt.Sprintf("%s %s-%s %s", days, w.Start, w.End, zone)
+}
+
+// validate checks the window's times, days and time zone
+func (w MaintenanceWindow) validate() error {
+	if _, err := w.location(); err != nil {
+		return err
+	}
+	if _, err := minuteOfDay(w.Start); err != nil {
+		return err
+	}
+	if _, err := minuteOfDay(w.End); err != nil {
+		return err
+	}
+	for _, day := range w.Days {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("invalid day %q", day)
+		}
+	}
+	return nil
+}
+
+// contains reports whether t falls inside the window
+func (w MaintenanceWindow) contains(t time.Time) (bool, error) {
+	loc, err := w.location()
+	if err != nil {
+		return false, err
+	}
+	start, err := minuteOfDay(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := minuteOfDay(w.End)
+	if err != nil {
+		return false, err
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	switch {
+	case start < end:
+		if minute < start || minute >= end {
+			return false, nil
+		}
+	case minute >= start:
+		// Inside a window that started today and runs past midnight
+	case minute < end:
+		// Inside a window that started yesterday
+		day = (day + 6) % 7
+	default:
+		return false, nil
+	}
+
+	return w.onDay(day), nil
+}
+
+func (w MaintenanceWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekday, ok := parseWeekday(d); ok && weekday == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (w MaintenanceWindow) location() (*time.Location, error) {
+	if w.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", w.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// minuteOfDay parses an "HH:MM" time
+func minuteOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseWeekday accepts full or three letter English day names
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// inMaintenanceWindow reports whether updates may run at t; no windows means always
+func (uc *UpdateController) inMaintenanceWindow(t time.Time) bool {
+	if len(uc.config.MaintenanceWindows) == 0 {
+		return true
+	}
+
+	for _, window := range uc.config.MaintenanceWindows {
+		inside, err := window.contains(t)
+		if err != nil {
+			klog.Warningf("Ignoring maintenance window %s: %v", window, err)
+			continue
+		}
+		if inside {
+			return true
+		}
+	}
+	return false
+}
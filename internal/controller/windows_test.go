@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	// 2024-05-01 is a Wednesday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		t      time.Time
+		want   bool
+	}{
+		{"inside", MaintenanceWindow{Start: "03:00", End: "05:00"}, at(1, 4, 0), true},
+		{"at start", MaintenanceWindow{Start: "03:00", End: "05:00"}, at(1, 3, 0), true},
+		{"at end", MaintenanceWindow{Start: "03:00", End: "05:00"}, at(1, 5, 0), false},
+		{"before", MaintenanceWindow{Start: "03:00", End: "05:00"}, at(1, 2, 59), false},
+		{"past midnight, evening", MaintenanceWindow{Start: "22:00", End: "02:00"}, at(1, 23, 0), true},
+		{"past midnight, morning", MaintenanceWindow{Start: "22:00", End: "02:00"}, at(1, 1, 0), true},
+		{"past midnight, outside", MaintenanceWindow{Start: "22:00", End: "02:00"}, at(1, 12, 0), false},
+		{"same start and end runs all day", MaintenanceWindow{Start: "04:00", End: "04:00"}, at(1, 3, 59), true},
+		{"on listed day", MaintenanceWindow{Days: []string{"Wed"}, Start: "03:00", End: "05:00"}, at(1, 4, 0), true},
+		{"on other day", MaintenanceWindow{Days: []string{"Tue"}, Start: "03:00", End: "05:00"}, at(1, 4, 0), false},
+		{"full day name", MaintenanceWindow{Days: []string{"wednesday"}, Start: "03:00", End: "05:00"}, at(1, 4, 0), true},
+		{"after midnight counts the start day", MaintenanceWindow{Days: []string{"Tue"}, Start: "22:00", End: "02:00"}, at(1, 1, 0), true},
+		{"after midnight on the wrong start day", MaintenanceWindow{Days: []string{"Wed"}, Start: "22:00", End: "02:00"}, at(1, 1, 0), false},
+		{"time zone", MaintenanceWindow{Start: "03:00", End: "05:00", TimeZone: "Europe/Berlin"}, at(1, 2, 0), true},
+		{"time zone outside", MaintenanceWindow{Start: "03:00", End: "05:00", TimeZone: "Europe/Berlin"}, at(1, 4, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.window.contains(tt.t)
+			if err != nil {
+				t.Fatalf("contains() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("%s contains(%s) = %v, want %v", tt.window, tt.t.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  MaintenanceWindow
+		wantErr bool
+	}{
+		{"valid", MaintenanceWindow{Days: []string{"Sat", "sunday"}, Start: "22:00", End: "02:00", TimeZone: "Europe/Berlin"}, false},
+		{"bad start", MaintenanceWindow{Start: "25:00", End: "02:00"}, true},
+		{"bad end", MaintenanceWindow{Start: "22:00", End: "2am"}, true},
+		{"bad day", MaintenanceWindow{Days: []string{"Someday"}, Start: "22:00", End: "02:00"}, true},
+		{"short day", MaintenanceWindow{Days: []string{"mo"}, Start: "22:00", End: "02:00"}, true},
+		{"bad time zone", MaintenanceWindow{Start: "22:00", End: "02:00", TimeZone: "Mars/Olympus"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.window.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInMaintenanceWindow(t *testing.T) {
+	at := time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		windows []MaintenanceWindow
+		want    bool
+	}{
+		{"no windows", nil, true},
+		{"inside one", []MaintenanceWindow{{Start: "01:00", End: "02:00"}, {Start: "03:00", End: "05:00"}}, true},
+		{"outside all", []MaintenanceWindow{{Start: "01:00", End: "02:00"}}, false},
+		{"invalid window ignored", []MaintenanceWindow{{Start: "bad", End: "05:00"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UpdateController{config: &Config{MaintenanceWindows: tt.windows}}
+			if got := uc.inMaintenanceWindow(at); got != tt.want {
+				t.Errorf("inMaintenanceWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Package metrics defines the Prometheus metrics exported by the controller
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "updatecontroller"
+
+// Labels identifying the install a metric belongs to; policy is empty outside policy mode
+var appLabels = []string{"app_id", "policy"}
+
+var (
+	// InstalledBuild is the build ID on the game volume
+	InstalledBuild = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "installed_build_id",
+		Help:      "Build ID installed on the game volume.",
+	}, appLabels)
+
+	// LatestBuild is the newest build ID Steam reported
+	LatestBuild = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "latest_build_id",
+		Help:      "Latest build ID reported by Steam for the tracked branch.",
+	}, appLabels)
+
+	// LastCheckTimestamp is when the last update check succeeded
+	LastCheckTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_check_timestamp_seconds",
+		Help:      "Unix time of the last successful update check.",
+	}, appLabels)
+
+	// LastUpdateTimestamp is when the last update completed successfully
+	LastUpdateTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_update_timestamp_seconds",
+		Help:      "Unix time of the last successful update.",
+	}, appLabels)
+
+	// UpdateInProgress is 1 while an update is running
+	UpdateInProgress = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "update_in_progress",
+		Help:      "1 while an update is being downloaded, validated or rolled out.",
+	}, appLabels)
+
+	// OutdatedPods is the number of target pods running an older build
+	OutdatedPods = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "outdated_pods",
+		Help:      "Target pods started before the installed build.",
+	}, appLabels)
+
+	// Checks counts update checks by result
+	Checks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checks_total",
+		Help:      "Update checks by result.",
+	}, append(appLabels, "result"))
+
+	// Updates counts updates by result
+	Updates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_total",
+		Help:      "Updates by result.",
+	}, append(appLabels, "result"))
+
+	// Failures counts failures by the stage that failed
+	Failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failures_total",
+		Help:      "Failures by class: check, download, validation, restart or log_scan.",
+	}, append(appLabels, "class"))
+
+	// StateRecoveries counts 0x6 error state recoveries
+	StateRecoveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "state_recoveries_total",
+		Help:      "Recoveries from the steamcmd 0x6 error state.",
+	}, appLabels)
+
+	// StageDuration observes how long each steamcmd stage took
+	StageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "steamcmd_stage_duration_seconds",
+		Help:      "Duration of steamcmd runs by stage.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 15),
+	}, append(appLabels, "stage", "result"))
+
+	// BytesDownloaded counts bytes downloaded by updates
+	BytesDownloaded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes downloaded by completed updates, from the app manifest.",
+	}, appLabels)
+
+	// WorkloadRestarts counts restart outcomes per workload
+	WorkloadRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "workload_restarts_total",
+		Help:      "Workload restart outcomes: succeeded, failed or skipped.",
+	}, append(appLabels, "namespace", "kind", "name", "outcome"))
+
+	// ConfigReloads counts config file changes by whether they were applied or rejected
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Config file changes by result: success when applied, failure when rejected as invalid.",
+	}, []string{"result"})
+)
+
+// BuildValue converts a Steam build ID to a gauge value, 0 when unknown
+func BuildValue(buildID string) float64 {
+	value, err := strconv.ParseFloat(buildID, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// Result labels an operation outcome
+func Result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
+
+// SetTimestamp sets a timestamp gauge to t
+func SetTimestamp(gauge prometheus.Gauge, t time.Time) {
+	gauge.Set(float64(t.Unix()))
+}
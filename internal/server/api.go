@@ -0,0 +1,76 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/UDL-TF/UpdateController/internal/controller"
+	"k8s.io/klog/v2"
+)
+
+// Controller is what the admin API reads status from and sends commands to
+type Controller interface {
+	Statuses() []controller.Status
+	Submit(policy string, command controller.Command) error
+	SubscribeProgress() (<-chan controller.ProgressEvent, func())
+}
+
+// AdminAPI serves status and manual commands, requiring token as a bearer token on every request
+func AdminAPI(ctrl Controller, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, ctrl.Statuses())
+	})
+
+	mux.Handle("GET /api/v1/events", progressStream(ctrl))
+
+	// Commands are queued for the controller's main loop and run one at a time
+	mux.HandleFunc("POST /api/v1/{command}", func(w http.ResponseWriter, r *http.Request) {
+		command := controller.Command(r.PathValue("command"))
+		policy := r.URL.Query().Get("policy")
+
+		err := ctrl.Submit(policy, command)
+		switch {
+		case err == nil:
+			klog.Infof("Admin API queued %s command (policy %q) from %s", command, policy, r.RemoteAddr)
+			writeJSON(w, http.StatusAccepted, map[string]string{"queued": string(command)})
+		case errors.Is(err, controller.ErrUnknownCommand), errors.Is(err, controller.ErrUnknownPolicy):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, controller.ErrCommandQueueFull):
+			writeError(w, http.StatusConflict, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+	})
+
+	return requireToken(token, mux)
+}
+
+// requireToken rejects requests without the expected bearer token
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="update-controller"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		klog.V(2).Infof("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
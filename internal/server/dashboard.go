@@ -0,0 +1,22 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// dashboardFiles are the web UI assets, embedded so the dashboard works without internet access
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// Dashboard serves the web UI under prefix; the UI reads and controls the
+// controller only through the admin API, using a token entered in the browser
+func Dashboard(prefix string) http.Handler {
+	assets, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(prefix, http.FileServerFS(assets))
+}
@@ -0,0 +1,373 @@
+"use strict";
+
+// The dashboard only talks to the admin API; the token stays in this browser tab
+const tokenKey = "updatecontroller-token";
+const pollInterval = 5000;
+
+let statuses = [];
+// Live progress per policy key ("" outside policy mode)
+const progress = new Map();
+let streamAbort = null;
+let pollTimer = null;
+
+const $ = (selector, root = document) => root.querySelector(selector);
+
+function token() {
+  return sessionStorage.getItem(tokenKey);
+}
+
+async function api(method, path) {
+  const response = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token() },
+  });
+  if (response.status === 401) {
+    logout("The admin token was rejected");
+    throw new Error("unauthorized");
+  }
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+async function refresh() {
+  try {
+    statuses = await api("GET", "/api/v1/status");
+    render();
+  } catch (err) {
+    console.warn("status refresh failed", err);
+  }
+}
+
+async function send(policy, command, button) {
+  button.disabled = true;
+  try {
+    const query = policy ? "?policy=" + encodeURIComponent(policy) : "";
+    await api("POST", "/api/v1/" + command + query);
+    setTimeout(refresh, 1000);
+  } catch (err) {
+    alert(command + " failed: " + err.message);
+  } finally {
+    button.disabled = false;
+  }
+}
+
+// stream reads the Server-Sent Events endpoint with fetch, since EventSource cannot send the token
+async function stream() {
+  streamAbort = new AbortController();
+  const connection = $("#connection");
+
+  while (token() && !streamAbort.signal.aborted) {
+    try {
+      const response = await fetch("/api/v1/events", {
+        headers: { Authorization: "Bearer " + token() },
+        signal: streamAbort.signal,
+      });
+      if (!response.ok) {
+        throw new Error(response.statusText);
+      }
+      connection.textContent = "live";
+      connection.classList.add("live");
+
+      const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buffer = "";
+      for (;;) {
+        const { value, done } = await reader.read();
+        if (done) {
+          break;
+        }
+        buffer += value;
+        let end;
+        while ((end = buffer.indexOf("\n\n")) >= 0) {
+          handleEvent(buffer.slice(0, end));
+          buffer = buffer.slice(end + 2);
+        }
+      }
+    } catch (err) {
+      if (streamAbort.signal.aborted) {
+        return;
+      }
+      console.warn("progress stream failed", err);
+    }
+
+    connection.textContent = "reconnecting";
+    connection.classList.remove("live");
+    await new Promise((resolve) => setTimeout(resolve, 3000));
+  }
+}
+
+function handleEvent(block) {
+  const data = block
+    .split("\n")
+    .filter((line) => line.startsWith("data: "))
+    .map((line) => line.slice(6))
+    .join("\n");
+  if (!data) {
+    return;
+  }
+
+  const event = JSON.parse(data);
+  const key = event.policy || "";
+  const current = progress.get(key) || {};
+  switch (event.type) {
+    case "phase":
+      current.phase = event.phase;
+      current.download = null;
+      if (event.phase !== "restarting") {
+        current.restart = null;
+      }
+      // Phase changes also change builds and history
+      refresh();
+      break;
+    case "download":
+      current.phase = event.phase;
+      current.download = event.download;
+      break;
+    case "restart":
+      current.restart = event.restart;
+      break;
+  }
+  progress.set(key, current);
+  render();
+}
+
+function render() {
+  const apps = $("#apps");
+  const template = $("#app-template");
+
+  apps.replaceChildren(
+    ...statuses.map((status) => {
+      const node = template.content.firstElementChild.cloneNode(true);
+      renderApp(node, status);
+      return node;
+    }),
+  );
+}
+
+function renderApp(node, status) {
+  const live = progress.get(status.policy || "") || {};
+
+  $(".app-name", node).textContent =
+    (status.policy ? status.policy + " · " : "") + `${status.app} (${status.appId}, ${status.branch})`;
+  $(".paused", node).hidden = !status.paused;
+  $(".deferred", node).hidden = !status.updateDeferred;
+  $(".dryrun", node).hidden = !status.dryRun;
+  const rolledBack = $(".rolledback", node);
+  rolledBack.hidden = !status.rolledBackFrom;
+  rolledBack.textContent = "rolled back from " + status.rolledBackFrom;
+
+  const installed = $(".installed", node);
+  installed.textContent = status.installedBuild || "not installed";
+  installed.classList.toggle("outdated", !!status.latestBuild && status.installedBuild !== status.latestBuild);
+  $(".latest", node).textContent = status.latestBuild || "unknown";
+
+  const lastCheck = $(".last-check", node);
+  lastCheck.textContent = status.lastCheck ? formatTime(status.lastCheck) : "never";
+  if (status.lastCheckError) {
+    lastCheck.textContent += " — " + status.lastCheckError;
+    lastCheck.classList.add("error");
+  }
+
+  const lastCommand = $(".last-command", node);
+  if (status.lastCommand) {
+    const cmd = status.lastCommand;
+    lastCommand.textContent = `${cmd.command} at ${formatTime(cmd.requestedAt)}` +
+      (cmd.finishedAt ? (cmd.error ? " failed: " + cmd.error : " done") : " running");
+    lastCommand.classList.toggle("error", !!cmd.error);
+  } else {
+    lastCommand.textContent = "none";
+  }
+
+  renderPhase(node, status, live);
+  renderPlan(node, status);
+  renderTargets(node, status);
+  renderHistory(node, status);
+
+  for (const button of node.querySelectorAll("[data-command]")) {
+    const command = button.dataset.command;
+    if (command === "pause") {
+      button.hidden = status.paused;
+    } else if (command === "resume") {
+      button.hidden = !status.paused;
+    } else if (command === "rollback") {
+      button.hidden = !status.rollbackBranch || status.phase !== "idle";
+    }
+    button.addEventListener("click", () => {
+      if (command === "rollback" &&
+        !confirm(`Install the ${status.rollbackBranch} branch, restart the servers onto it and pause updates?`)) {
+        return;
+      }
+      send(status.policy, command, button);
+    });
+  }
+}
+
+function renderPhase(node, status, live) {
+  const phase = live.phase || status.phase;
+  $(".phase-name", node).textContent = phase;
+
+  const detail = $(".phase-detail", node);
+  const bar = $(".phase-progress", node);
+  const download = live.download;
+  if (download && phase !== "idle") {
+    bar.hidden = false;
+    bar.value = download.percent;
+    detail.textContent = `${download.state} ${download.percent.toFixed(1)}% ` +
+      `(${formatBytes(download.bytesDone)} / ${formatBytes(download.bytesTotal)})` +
+      (download.bytesPerSecond ? `, ${formatBytes(download.bytesPerSecond)}/s` : "") +
+      (download.etaSeconds ? `, ETA ${formatDuration(download.etaSeconds)}` : "");
+  } else if (phase !== "idle" && status.phaseStartedAt) {
+    detail.textContent = "since " + formatTime(status.phaseStartedAt);
+  }
+
+  const restart = live.restart;
+  if (restart) {
+    const state = $(".restart-detail", node);
+    state.textContent = `Restarts ${restart.done}/${restart.total}: ${restart.kind} ${restart.namespace}/${restart.name} ` +
+      `${restart.state} (${restart.strategy})` + (restart.error ? ": " + restart.error : "");
+    state.classList.toggle("failed", restart.state === "failed");
+  }
+}
+
+function renderPlan(node, status) {
+  const plan = status.plan;
+  if (!plan) {
+    return;
+  }
+  $(".plan", node).hidden = false;
+  $(".plan-summary", node).textContent = `${plan.trigger}` +
+    (plan.targetBuild ? ` to build ${plan.targetBuild}` : "") +
+    ` at ${formatTime(plan.generatedAt)}: ` +
+    [...(plan.steps || []), ...(plan.recovery || []).map((r) => "recovery: " + r)].join("; ");
+  $(".plan-restarts", node).replaceChildren(
+    ...(plan.restart || []).map((restart) => {
+      const item = document.createElement("li");
+      item.textContent = `${restart.kind} ${restart.namespace}/${restart.name}: ${restart.policy}, ${restart.strategy}, ${restart.pods} pods`;
+      return item;
+    }),
+  );
+}
+
+function renderTargets(node, status) {
+  const rows = [];
+  for (const target of status.targets || []) {
+    for (const pod of target.pods || []) {
+      const row = document.createElement("tr");
+      const build = pod.outdated ? "older than " + (status.installedBuild || "install") : pod.build;
+      row.append(
+        cell(`${target.kind} ${target.namespace}/${target.name}`),
+        cell(pod.name),
+        cell(build, pod.outdated ? "outdated" : ""),
+        cell(pod.startedAt ? formatDuration((Date.now() - Date.parse(pod.startedAt)) / 1000) : "unknown"),
+      );
+      rows.push(row);
+    }
+  }
+  if (rows.length === 0) {
+    rows.push(emptyRow(4, "No target pods"));
+  }
+  $(".targets tbody", node).replaceChildren(...rows);
+}
+
+function renderHistory(node, status) {
+  const rows = (status.history || []).map((result) => {
+    const row = document.createElement("tr");
+    const restart = result.restart;
+    row.append(
+      cell(formatTime(result.startedAt)),
+      cell(formatDuration((Date.parse(result.finishedAt) - Date.parse(result.startedAt)) / 1000)),
+      cell(result.success ? "succeeded" : "failed: " + result.error, result.success ? "succeeded" : "failed"),
+      cell(restart
+        ? `${(restart.succeeded || []).length} restarted, ${(restart.failed || []).length} failed, ${(restart.skipped || []).length} skipped`
+        : "none"),
+    );
+    return row;
+  });
+  if (rows.length === 0) {
+    rows.push(emptyRow(4, "No updates since the controller started"));
+  }
+  $(".history tbody", node).replaceChildren(...rows);
+}
+
+function cell(text, className) {
+  const td = document.createElement("td");
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function emptyRow(columns, text) {
+  const row = document.createElement("tr");
+  const td = cell(text);
+  td.colSpan = columns;
+  row.append(td);
+  return row;
+}
+
+function formatTime(value) {
+  return new Date(value).toLocaleString();
+}
+
+function formatBytes(bytes) {
+  const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  while (bytes >= 1024 && i < units.length - 1) {
+    bytes /= 1024;
+    i++;
+  }
+  return bytes.toFixed(i === 0 ? 0 : 1) + " " + units[i];
+}
+
+function formatDuration(seconds) {
+  seconds = Math.max(0, Math.round(seconds));
+  const d = Math.floor(seconds / 86400);
+  const h = Math.floor((seconds % 86400) / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  if (d > 0) return `${d}d ${h}h`;
+  if (h > 0) return `${h}h ${m}m`;
+  if (m > 0) return `${m}m ${seconds % 60}s`;
+  return `${seconds}s`;
+}
+
+function start() {
+  $("#login").hidden = true;
+  $("#logout").hidden = false;
+  refresh();
+  pollTimer = setInterval(refresh, pollInterval);
+  stream();
+}
+
+function logout(message) {
+  sessionStorage.removeItem(tokenKey);
+  clearInterval(pollTimer);
+  if (streamAbort) {
+    streamAbort.abort();
+  }
+  statuses = [];
+  progress.clear();
+  render();
+  $("#connection").textContent = "disconnected";
+  $("#connection").classList.remove("live");
+  $("#logout").hidden = true;
+  $("#login").hidden = false;
+  $("#login-error").textContent = message || "";
+}
+
+$("#login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  sessionStorage.setItem(tokenKey, $("#token").value);
+  $("#token").value = "";
+  start();
+});
+
+$("#logout").addEventListener("click", () => logout());
+
+if (token()) {
+  start();
+} else {
+  logout();
+}
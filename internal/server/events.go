@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// keepAliveInterval is how often an idle event stream gets a comment so proxies keep it open
+const keepAliveInterval = 15 * time.Second
+
+// progressStream serves live progress as Server-Sent Events, optionally filtered by ?policy=
+func progressStream(ctrl Controller) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+			return
+		}
+		policy := r.URL.Query().Get("policy")
+
+		events, unsubscribe := ctrl.SubscribeProgress()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Stops nginx-style proxies from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		klog.V(2).Infof("Progress stream opened by %s", r.RemoteAddr)
+		defer klog.V(2).Infof("Progress stream closed by %s", r.RemoteAddr)
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if policy != "" && event.Policy != policy {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					klog.Warningf("Failed to encode progress event: %v", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// Package server runs the controller's HTTP endpoints
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 5 * time.Second
+
+// Server is an HTTP server whose handlers are registered before Run
+type Server struct {
+	addr string
+	mux  *http.ServeMux
+}
+
+// New creates a server listening on addr
+func New(addr string) *Server {
+	return &Server{
+		addr: addr,
+		mux:  http.NewServeMux(),
+	}
+}
+
+// Handle registers a handler for a ServeMux pattern
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run serves until the context is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Cancelling request contexts on shutdown ends long-lived event streams
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			klog.Warningf("HTTP server shutdown: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving HTTP on %s", s.addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("http server failed: %w", err)
+	}
+	return nil
+}
+
+// Probe serves a health check: 200 "ok" when check passes, 503 with the error otherwise
+func Probe(check func(ctx context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(r.Context()); err != nil {
+			klog.V(2).Infof("%s failed: %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
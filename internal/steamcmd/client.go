@@ -11,10 +11,14 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 )
 
+// waitDelay is how long a killed steamcmd gets to release its output pipes
+const waitDelay = 10 * time.Second
+
 // Client handles SteamCMD operations for TF2 updates
 type Client struct {
 	steamCMDPath  string
@@ -22,6 +26,13 @@ type Client struct {
 	steamAppID    string
 	gameMountPath string
 	updateScript  string
+	branch        string
+	timeout       time.Duration
+	hooks         Hooks
+
+	// latestBuildID is the latest build seen by the last CheckUpdate
+	latestBuildID string
+	mu            sync.Mutex
 }
 
 // NewClient creates a new SteamCMD client
@@ -32,7 +43,49 @@ func NewClient(steamCMDPath, steamApp, steamAppID, gameMountPath, updateScript s
 		steamAppID:    steamAppID,
 		gameMountPath: gameMountPath,
 		updateScript:  updateScript,
+		branch:        "public",
+	}
+}
+
+// SetBranch selects the Steam beta branch to install and track, "public" by default
+func (c *Client) SetBranch(branch string) {
+	if branch == "" {
+		branch = "public"
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The latest build seen belongs to the previous branch
+	if c.branch != branch {
+		c.latestBuildID = ""
+	}
+	c.branch = branch
+}
+
+// SetSteamCMDPath changes the directory holding steamcmd.sh used by later runs
+func (c *Client) SetSteamCMDPath(steamCMDPath string) {
+	c.steamCMDPath = steamCMDPath
+}
+
+// SetUpdateScript changes the runscript file name, relative to the game mount path, used by later runs
+func (c *Client) SetUpdateScript(updateScript string) {
+	c.updateScript = updateScript
+}
+
+// LatestBuild returns the latest build ID seen by the last CheckUpdate, empty if unknown
+func (c *Client) LatestBuild() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latestBuildID
+}
+
+// DescribeUpdate returns the steamcmd invocation ApplyUpdate (or, with validate, ValidateUpdate) would run
+func (c *Client) DescribeUpdate(validate bool) string {
+	command := fmt.Sprintf("steamcmd +force_install_dir %s +login anonymous +app_update %s%s", c.gameMountPath, c.steamAppID, c.betaFlag())
+	if validate {
+		command += " validate"
+	}
+	return command
 }
 
 // isGameInstalled checks if the game is already installed
@@ -67,32 +120,43 @@ func (c *Client) isGameInstalled() bool {
 func (c *Client) CheckUpdate(ctx context.Context) (bool, error) {
 	klog.V(2).Info("Checking for updates by comparing build IDs")
 
+	// Resolve the latest build first so a missing or incomplete install still has a target
+	latestBuildID, err := c.getLatestBuildID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get latest build ID: %w", err)
+	}
+
+	klog.V(2).Infof("Latest build ID: %s", latestBuildID)
+
+	c.mu.Lock()
+	c.latestBuildID = latestBuildID
+	c.mu.Unlock()
+
 	// Check if game is installed at all
 	if !c.isGameInstalled() {
 		klog.Info("Game not installed, initial installation required")
 		return true, nil
 	}
 
-	// Get the currently installed build ID
-	installedBuildID, err := c.getInstalledBuildID()
+	// The installed build ID and install state come from one manifest read
+	state, _, err := c.InstallState()
 	if err != nil {
-		return false, fmt.Errorf("failed to get installed build ID: %w", err)
+		return false, fmt.Errorf("failed to read install state: %w", err)
 	}
+	installedBuildID := state.BuildID
 
 	if installedBuildID == "" {
 		klog.Info("No build ID found in manifest, assuming update needed")
 		return true, nil
 	}
 
-	klog.V(2).Infof("Installed build ID: %s", installedBuildID)
-
-	// Get the latest available build ID from Steam
-	latestBuildID, err := c.getLatestBuildID(ctx)
-	if err != nil {
-		return false, fmt.Errorf("failed to get latest build ID: %w", err)
+	// An interrupted app_update leaves the new build ID behind with incomplete files
+	if !state.FullyInstalled() {
+		klog.Warningf("Install is not complete (%s), update needed", state)
+		return true, nil
 	}
 
-	klog.V(2).Infof("Latest build ID: %s", latestBuildID)
+	klog.V(2).Infof("Installed build ID: %s", installedBuildID)
 
 	// Compare build IDs
 	if installedBuildID != latestBuildID {
@@ -112,38 +176,83 @@ func (c *Client) ApplyUpdate(ctx context.Context) error {
 		klog.Info("Applying TF2 update via SteamCMD")
 	}
 
+	return c.runUpdateScript(ctx, false, "update")
+}
+
+// Repair runs app_update with validate, which re-downloads missing or corrupt
+// files and finishes an interrupted update
+func (c *Client) Repair(ctx context.Context) error {
+	klog.Info("Repairing TF2 installation via SteamCMD")
+	return c.runUpdateScript(ctx, true, "repair")
+}
+
+// runUpdateScript runs app_update, recovering once from a 0x6 error state
+func (c *Client) runUpdateScript(ctx context.Context, validate bool, stage string) error {
 	scriptPath := filepath.Join(c.gameMountPath, c.updateScript)
-	if err := c.createUpdateScript(scriptPath, false); err != nil {
+	if err := c.createUpdateScript(scriptPath, validate); err != nil {
 		return fmt.Errorf("failed to create update script: %w", err)
 	}
 
-	output, err := c.runSteamCMD(ctx, scriptPath, "update")
+	output, err := c.runSteamCMD(ctx, scriptPath, stage)
 
 	// Check for 0x6 error state and attempt recovery
 	if c.hasState0x6Error(output) {
 		klog.Warning("Detected 0x6 error state, attempting recovery...")
+		c.recovered(fmt.Sprintf("steamcmd %s hit a 0x6 error state, clearing steamapps and retrying", stage))
 		if err := c.clearSteamApps(); err != nil {
 			return fmt.Errorf("failed to clear steamapps for recovery: %w", err)
 		}
 
 		// Retry update after clearing steamapps
 		klog.Info("Retrying update after clearing steamapps...")
-		output, err = c.runSteamCMD(ctx, scriptPath, "update-retry")
+		output, err = c.runSteamCMD(ctx, scriptPath, stage+"-retry")
 
 		if err != nil {
-			return fmt.Errorf("steamcmd update failed after 0x6 recovery: %w, output: %s", err, string(output))
+			return fmt.Errorf("steamcmd %s failed after 0x6 recovery: %w, output: %s", stage, err, string(output))
 		}
 	} else if err != nil {
-		return fmt.Errorf("steamcmd update failed: %w, output: %s", err, string(output))
+		return fmt.Errorf("steamcmd %s failed: %w, output: %s", stage, err, string(output))
 	}
 
 	if !strings.Contains(string(output), "Success") {
-		return fmt.Errorf("update may have failed, check output: %s", string(output))
+		return fmt.Errorf("%s may have failed, check output: %s", stage, string(output))
 	}
 
 	return nil
 }
 
+// Rollback installs and validates the build of another branch, typically a
+// beta branch holding the previous release, in place of the tracked branch
+func (c *Client) Rollback(ctx context.Context, branch string) error {
+	klog.Infof("Rolling back to the build of branch %s via SteamCMD", branch)
+
+	scriptPath := filepath.Join(c.gameMountPath, "rollback_script.txt")
+	script := fmt.Sprintf(`@ShutdownOnFailedCommand 1
+@NoPromptForPassword 1
+force_install_dir %s
+login anonymous
+app_update %s%s validate
+quit
+`, c.gameMountPath, c.steamAppID, betaFlag(branch))
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		return fmt.Errorf("failed to write rollback script: %w", err)
+	}
+
+	output, err := c.runSteamCMD(ctx, scriptPath, "rollback")
+	if err != nil {
+		return fmt.Errorf("steamcmd rollback failed: %w, output: %s", err, string(output))
+	}
+	if !strings.Contains(string(output), "Success") {
+		return fmt.Errorf("rollback may have failed, check output: %s", string(output))
+	}
+	return nil
+}
+
+// DescribeRollback returns the steamcmd invocation Rollback would run
+func (c *Client) DescribeRollback(branch string) string {
+	return fmt.Sprintf("steamcmd +force_install_dir %s +login anonymous +app_update %s%s validate", c.gameMountPath, c.steamAppID, betaFlag(branch))
+}
+
 // ValidateUpdate validates the installed game files
 func (c *Client) ValidateUpdate(ctx context.Context) error {
 	klog.Info("Validating TF2 installation")
@@ -177,9 +286,9 @@ func (c *Client) createUpdateScript(scriptPath string, validateOnly bool) error
 @NoPromptForPassword 1
 force_install_dir %s
 login anonymous
-app_update %s %s
+app_update %s%s %s
 quit
-`, c.gameMountPath, c.steamAppID, validateFlag)
+`, c.gameMountPath, c.steamAppID, c.betaFlag(), validateFlag)
 
 	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
 		return fmt.Errorf("failed to write script file: %w", err)
@@ -195,9 +304,9 @@ func (c *Client) createValidateScript(scriptPath string) error {
 @NoPromptForPassword 1
 force_install_dir %s
 login anonymous
-app_update %s validate
+app_update %s%s validate
 quit
-`, c.gameMountPath, c.steamAppID)
+`, c.gameMountPath, c.steamAppID, c.betaFlag())
 
 	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
 		return fmt.Errorf("failed to write script file: %w", err)
@@ -206,6 +315,19 @@ quit
 	return nil
 }
 
+// betaFlag returns the app_update argument selecting the tracked branch
+func (c *Client) betaFlag() string {
+	return betaFlag(c.branch)
+}
+
+// betaFlag returns the app_update argument selecting a non-public branch
+func betaFlag(branch string) string {
+	if branch == "" || branch == "public" {
+		return ""
+	}
+	return " -beta " + branch
+}
+
 // hasState0x6Error checks if the output contains the 0x6 error state
 func (c *Client) hasState0x6Error(output []byte) bool {
 	outputStr := string(output)
@@ -229,30 +351,52 @@ func (c *Client) clearSteamApps() error {
 
 // getInstalledBuildID reads the installed build ID from the local manifest file
 func (c *Client) getInstalledBuildID() (string, error) {
+	buildID, _, err := c.InstalledBuild()
+	return buildID, err
+}
+
+// InstalledBuild returns the installed build ID and the time Steam last
+// updated the install, both read from the local manifest. An empty build ID
+// means the game is not installed
+func (c *Client) InstalledBuild() (string, time.Time, error) {
+	m, found, err := c.readManifest()
+	if err != nil || !found {
+		return "", time.Time{}, err
+	}
+
+	buildID := m.value("buildid")
+	if buildID == "" {
+		return "", time.Time{}, fmt.Errorf("buildid not found in manifest")
+	}
+	return buildID, m.lastUpdated(), nil
+}
+
+// InstallState returns the install state recorded in the app manifest. found
+// is false when the manifest does not exist. Every field comes from a single
+// read, so a manifest steamcmd rewrites meanwhile is never mixed
+func (c *Client) InstallState() (state InstallState, found bool, err error) {
+	m, found, err := c.readManifest()
+	if err != nil || !found {
+		return InstallState{}, false, err
+	}
+
+	state, err = m.installState()
+	return state, true, err
+}
+
+// readManifest reads and parses the app manifest. found is false when the
+// manifest does not exist
+func (c *Client) readManifest() (m manifest, found bool, err error) {
 	manifestPath := filepath.Join(c.gameMountPath, "steamapps", fmt.Sprintf("appmanifest_%s.acf", c.steamAppID))
 
 	data, err := os.ReadFile(manifestPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", nil // Not installed
-		}
-		return "", fmt.Errorf("failed to read manifest: %w", err)
-	}
-
-	// Parse the ACF file for buildid
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "\"buildid\"") {
-			// Format: "buildid"		"12345678"
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				buildID := strings.Trim(parts[1], "\"")
-				return buildID, nil
-			}
+			return nil, false, nil // Not installed
 		}
+		return nil, false, fmt.Errorf("failed to read manifest: %w", err)
 	}
-
-	return "", fmt.Errorf("buildid not found in manifest")
+	return parseManifest(data), true, nil
 }
 
 // getLatestBuildID queries SteamCMD for the latest available build ID without downloading
@@ -271,29 +415,33 @@ quit
 	}
 	defer os.Remove(scriptPath)
 
+	ctx, finish := c.startStage(ctx, "app_info")
 	cmd := exec.CommandContext(ctx, c.steamCMDPath+"/steamcmd.sh", "+runscript", scriptPath)
+	cmd.WaitDelay = waitDelay
 	output, err := cmd.CombinedOutput()
+	finish(err)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to query app info: %w, output: %s", err, string(output))
 	}
 
-	// Parse the output for the buildid in the public branch
+	// Parse the output for the buildid in the tracked branch
 	// Look for: "buildid"		"12345678" in the "branches" -> "public" section
 	outputStr := string(output)
 	lines := strings.Split(outputStr, "\n")
-	inPublicBranch := false
+	branchKey := fmt.Sprintf("\"%s\"", c.branch)
+	inBranch := false
 
 	for i, line := range lines {
-		if strings.Contains(line, "\"public\"") {
-			inPublicBranch = true
+		if strings.Contains(line, branchKey) {
+			inBranch = true
 			continue
 		}
 
-		if inPublicBranch {
-			// Check if we've exited the public branch section
+		if inBranch {
+			// Check if we've exited the branch section
 			if strings.Contains(line, "\"}") && !strings.Contains(line, "\"buildid\"") {
-				inPublicBranch = false
+				inBranch = false
 				continue
 			}
 
@@ -306,8 +454,8 @@ quit
 			}
 		}
 
-		// Alternative: look for buildid directly after app ID section
-		if strings.Contains(line, fmt.Sprintf("\"%s\"", c.steamAppID)) {
+		// Alternative: look for buildid directly after app ID section, which is the public build
+		if c.branch == "public" && strings.Contains(line, fmt.Sprintf("\"%s\"", c.steamAppID)) {
 			// Scan next ~50 lines for buildid in common section
 			for j := i; j < i+50 && j < len(lines); j++ {
 				if strings.Contains(lines[j], "\"buildid\"") && !strings.Contains(lines[j], "branches") {
@@ -327,8 +475,13 @@ quit
 }
 
 // runSteamCMD executes steamcmd scripts while streaming progress output.
-func (c *Client) runSteamCMD(ctx context.Context, scriptPath, stage string) ([]byte, error) {
+func (c *Client) runSteamCMD(ctx context.Context, scriptPath, stage string) (output []byte, err error) {
+	ctx, finish := c.startStage(ctx, stage)
+	defer func() { finish(err) }()
+
 	cmd := exec.CommandContext(ctx, c.steamCMDPath+"/steamcmd.sh", "+runscript", scriptPath)
+	// steamcmd.sh forks the real binary, which can hold the pipes open after a kill
+	cmd.WaitDelay = waitDelay
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -362,6 +515,8 @@ func (c *Client) runSteamCMD(ctx context.Context, scriptPath, stage string) ([]b
 				continue
 			}
 
+			c.reportProgress(stage, trimmed)
+
 			if c.shouldLogProgress(trimmed) {
 				klog.Infof("[%s:%s] %s", stage, stream, trimmed)
 			} else {
@@ -0,0 +1,59 @@
+package steamcmd
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks lets callers observe steamcmd runs; any field may be nil
+type Hooks struct {
+	// StageStarted is called when a steamcmd run starts, with the time it will be killed
+	StageStarted func(stage string, deadline time.Time)
+	// StageFinished is called when a steamcmd run exits
+	StageFinished func(stage string, duration time.Duration, err error)
+	// Recovery is called when an update recovers from a 0x6 error state
+	Recovery func(message string)
+	// Progress is called for every download or verification progress line,
+	// from the goroutine reading steamcmd output
+	Progress func(progress Progress)
+}
+
+// SetHooks registers the hooks called during steamcmd runs
+func (c *Client) SetHooks(hooks Hooks) {
+	c.hooks = hooks
+}
+
+// SetTimeout bounds every steamcmd run; 0 means no limit
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// startStage applies the run timeout and reports the start of a stage. The
+// returned function reports the end of the stage and releases the timeout
+func (c *Client) startStage(ctx context.Context, stage string) (context.Context, func(error)) {
+	startedAt := time.Now()
+
+	var deadline time.Time
+	cancel := context.CancelFunc(func() {})
+	if c.timeout > 0 {
+		deadline = startedAt.Add(c.timeout)
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+
+	if c.hooks.StageStarted != nil {
+		c.hooks.StageStarted(stage, deadline)
+	}
+
+	return ctx, func(err error) {
+		cancel()
+		if c.hooks.StageFinished != nil {
+			c.hooks.StageFinished(stage, time.Since(startedAt), err)
+		}
+	}
+}
+
+func (c *Client) recovered(message string) {
+	if c.hooks.Recovery != nil {
+		c.hooks.Recovery(message)
+	}
+}
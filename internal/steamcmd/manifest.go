@@ -0,0 +1,143 @@
+package steamcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Steam app state flags recorded as StateFlags in an appmanifest
+const (
+	stateUninstalled    = 1 << 0
+	stateUpdateRequired = 1 << 1
+	stateFullyInstalled = 1 << 2
+	stateFilesMissing   = 1 << 5
+	stateFilesCorrupt   = 1 << 7
+	stateUpdateRunning  = 1 << 8
+	stateUpdatePaused   = 1 << 9
+	stateUpdateStarted  = 1 << 10
+	stateValidating     = 1 << 17
+	stateDownloading    = 1 << 20
+	stateStaging        = 1 << 21
+	stateCommitting     = 1 << 22
+)
+
+// stateFlagNames names the flags reported when describing an install
+var stateFlagNames = []struct {
+	flag int64
+	name string
+}{
+	{stateUninstalled, "Uninstalled"},
+	{stateUpdateRequired, "UpdateRequired"},
+	{stateFullyInstalled, "FullyInstalled"},
+	{stateFilesMissing, "FilesMissing"},
+	{stateFilesCorrupt, "FilesCorrupt"},
+	{stateUpdateRunning, "UpdateRunning"},
+	{stateUpdatePaused, "UpdatePaused"},
+	{stateUpdateStarted, "UpdateStarted"},
+	{stateValidating, "Validating"},
+	{stateDownloading, "Downloading"},
+	{stateStaging, "Staging"},
+	{stateCommitting, "Committing"},
+}
+
+// InstallState is the install progress Steam records in the app manifest
+type InstallState struct {
+	BuildID         string
+	StateFlags      int64
+	BytesToDownload int64
+	BytesDownloaded int64
+	UpdateResult    int64
+}
+
+// FullyInstalled reports whether the manifest describes a complete, healthy
+// install: only the FullyInstalled flag, every byte downloaded and no update error
+func (s InstallState) FullyInstalled() bool {
+	return s.StateFlags == stateFullyInstalled &&
+		s.BytesDownloaded >= s.BytesToDownload &&
+		s.UpdateResult == 0
+}
+
+func (s InstallState) String() string {
+	var flags []string
+	for _, f := range stateFlagNames {
+		if s.StateFlags&f.flag != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+	if len(flags) == 0 {
+		flags = append(flags, "Invalid")
+	}
+
+	return fmt.Sprintf("build %s, StateFlags %d (%s), downloaded %d/%d bytes, UpdateResult %d",
+		s.BuildID, s.StateFlags, strings.Join(flags, "|"), s.BytesDownloaded, s.BytesToDownload, s.UpdateResult)
+}
+
+// manifest holds the top-level values of an appmanifest, keyed in lower case
+type manifest map[string]string
+
+// parseManifest reads the top-level key/value pairs of an appmanifest, e.g.
+// "buildid"		"12345678" inside the AppState block. Nested blocks such as
+// InstalledDepots are skipped, and the first occurrence of a key wins
+func parseManifest(data []byte) manifest {
+	values := make(manifest)
+	depth := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		switch {
+		case len(parts) == 0:
+		case parts[0] == "{":
+			depth++
+		case parts[0] == "}":
+			depth--
+		case depth == 1 && len(parts) >= 2:
+			key := strings.ToLower(strings.Trim(parts[0], `"`))
+			if _, ok := values[key]; !ok {
+				values[key] = strings.Trim(parts[1], `"`)
+			}
+		}
+	}
+	return values
+}
+
+// value returns a top-level value, "" when it is missing
+func (m manifest) value(key string) string {
+	return m[strings.ToLower(key)]
+}
+
+// installState takes the install progress from the manifest; missing counts are 0
+func (m manifest) installState() (InstallState, error) {
+	state := InstallState{BuildID: m.value("buildid")}
+	values := []struct {
+		key  string
+		dest *int64
+	}{
+		{"StateFlags", &state.StateFlags},
+		{"BytesToDownload", &state.BytesToDownload},
+		{"BytesDownloaded", &state.BytesDownloaded},
+		{"UpdateResult", &state.UpdateResult},
+	}
+
+	for _, v := range values {
+		value := m.value(v.key)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return InstallState{}, fmt.Errorf("invalid %s %q in manifest", v.key, value)
+		}
+		*v.dest = parsed
+	}
+	return state, nil
+}
+
+// lastUpdated returns when Steam last updated the install, zero when unknown
+func (m manifest) lastUpdated() time.Time {
+	seconds, err := strconv.ParseInt(m.value("LastUpdated"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,176 @@
+package steamcmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// acf renders an appmanifest holding the given key/value pairs, the way Steam writes it
+func acf(pairs ...string) string {
+	var b strings.Builder
+	b.WriteString("\"AppState\"\n{\n\t\"appid\"\t\t\"232250\"\n")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		b.WriteString("\t\"" + pairs[i] + "\"\t\t\"" + pairs[i+1] + "\"\n")
+	}
+	b.WriteString("\t\"InstalledDepots\"\n\t{\n\t\t\"232256\"\n\t\t{\n\t\t\t\"manifest\"\t\t\"5717283432415104343\"\n\t\t}\n\t}\n}\n")
+	return b.String()
+}
+
+// manifestClient returns a client whose game volume holds manifest, or no manifest when it is empty
+func manifestClient(t *testing.T, manifest string) *Client {
+	t.Helper()
+	dir := t.TempDir()
+	if manifest != "" {
+		steamapps := filepath.Join(dir, "steamapps")
+		if err := os.MkdirAll(steamapps, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(steamapps, "appmanifest_232250.acf"), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewClient(t.TempDir(), "tf", "232250", dir, "tf_update.txt")
+}
+
+func TestInstallState(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifest  string
+		want      InstallState
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name:     "no manifest",
+			manifest: "",
+		},
+		{
+			name:      "fully installed",
+			manifest:  acf("StateFlags", "4", "buildid", "12345678", "BytesToDownload", "1000", "BytesDownloaded", "1000", "UpdateResult", "0"),
+			want:      InstallState{BuildID: "12345678", StateFlags: 4, BytesToDownload: 1000, BytesDownloaded: 1000},
+			wantFound: true,
+		},
+		{
+			name:      "interrupted download",
+			manifest:  acf("StateFlags", "1026", "buildid", "12345678", "BytesToDownload", "1000", "BytesDownloaded", "250", "UpdateResult", "0"),
+			want:      InstallState{BuildID: "12345678", StateFlags: 1026, BytesToDownload: 1000, BytesDownloaded: 250},
+			wantFound: true,
+		},
+		{
+			name:      "failed update",
+			manifest:  acf("StateFlags", "6", "buildid", "12345678", "UpdateResult", "5"),
+			want:      InstallState{BuildID: "12345678", StateFlags: 6, UpdateResult: 5},
+			wantFound: true,
+		},
+		{
+			name:      "keys in another case",
+			manifest:  acf("stateflags", "4", "BuildID", "12345678"),
+			want:      InstallState{BuildID: "12345678", StateFlags: 4},
+			wantFound: true,
+		},
+		{
+			name:      "nested keys ignored",
+			manifest:  "\"AppState\"\n{\n\t\"UserConfig\"\n\t{\n\t\t\"buildid\"\t\t\"1\"\n\t}\n\t\"buildid\"\t\t\"12345678\"\n\t\"StateFlags\"\t\t\"4\"\n}\n",
+			want:      InstallState{BuildID: "12345678", StateFlags: 4},
+			wantFound: true,
+		},
+		{
+			name:      "partial manifest",
+			manifest:  acf("buildid", "12345678"),
+			want:      InstallState{BuildID: "12345678"},
+			wantFound: true,
+		},
+		{
+			name:      "truncated line",
+			manifest:  "\"AppState\"\n{\n\t\"StateFlags\"\t\t\"4\"\n\t\"buildid\"",
+			want:      InstallState{StateFlags: 4},
+			wantFound: true,
+		},
+		{
+			name:      "empty file",
+			manifest:  "\n",
+			wantFound: true,
+		},
+		{
+			name:      "non-numeric StateFlags",
+			manifest:  acf("StateFlags", "garbage", "buildid", "12345678"),
+			wantFound: true,
+			wantErr:   true,
+		},
+		{
+			name:      "non-numeric byte count",
+			manifest:  acf("StateFlags", "4", "BytesDownloaded", "12kb"),
+			wantFound: true,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := manifestClient(t, tt.manifest).InstallState()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstallState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound {
+				t.Errorf("InstallState() found = %v, want %v", found, tt.wantFound)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("InstallState() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallStateFullyInstalled(t *testing.T) {
+	tests := []struct {
+		name  string
+		state InstallState
+		want  bool
+	}{
+		{"complete", InstallState{StateFlags: stateFullyInstalled, BytesToDownload: 1000, BytesDownloaded: 1000}, true},
+		{"nothing to download", InstallState{StateFlags: stateFullyInstalled}, true},
+		{"update required", InstallState{StateFlags: stateFullyInstalled | stateUpdateRequired}, false},
+		{"update started", InstallState{StateFlags: stateFullyInstalled | stateUpdateStarted}, false},
+		{"files corrupt", InstallState{StateFlags: stateFullyInstalled | stateFilesCorrupt}, false},
+		{"bytes missing", InstallState{StateFlags: stateFullyInstalled, BytesToDownload: 1000, BytesDownloaded: 999}, false},
+		{"update error", InstallState{StateFlags: stateFullyInstalled, UpdateResult: 5}, false},
+		{"no flags", InstallState{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.FullyInstalled(); got != tt.want {
+				t.Errorf("FullyInstalled() = %v, want %v for %s", got, tt.want, tt.state)
+			}
+		})
+	}
+}
+
+func TestInstallStateString(t *testing.T) {
+	tests := []struct {
+		name  string
+		state InstallState
+		want  string
+	}{
+		{
+			name:  "known flags",
+			state: InstallState{BuildID: "12345678", StateFlags: stateUpdateRequired | stateUpdateStarted, BytesToDownload: 1000, BytesDownloaded: 250},
+			want:  "build 12345678, StateFlags 1026 (UpdateRequired|UpdateStarted), downloaded 250/1000 bytes, UpdateResult 0",
+		},
+		{
+			name:  "no flags",
+			state: InstallState{BuildID: "12345678"},
+			want:  "build 12345678, StateFlags 0 (Invalid), downloaded 0/0 bytes, UpdateResult 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package steamcmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// progressPattern matches app_update progress lines such as
+// "Update state (0x61) downloading, progress: 12.34 (1234567 / 10000000)"
+var progressPattern = regexp.MustCompile(`Update state \((0x[0-9a-fA-F]+)\) ([^,]+), progress: ([0-9.]+) \((\d+) / (\d+)\)`)
+
+// Progress is one progress line reported by steamcmd during app_update
+type Progress struct {
+	Stage      string
+	State      string
+	Percent    float64
+	BytesDone  int64
+	BytesTotal int64
+}
+
+// parseProgress extracts download or verification progress from a steamcmd output line
+func parseProgress(stage, line string) (Progress, bool) {
+	match := progressPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Progress{}, false
+	}
+
+	percent, err := strconv.ParseFloat(match[3], 64)
+	if err != nil {
+		return Progress{}, false
+	}
+	done, _ := strconv.ParseInt(match[4], 10, 64)
+	total, _ := strconv.ParseInt(match[5], 10, 64)
+
+	return Progress{
+		Stage:      stage,
+		State:      strings.TrimSpace(match[2]),
+		Percent:    percent,
+		BytesDone:  done,
+		BytesTotal: total,
+	}, true
+}
+
+func (c *Client) reportProgress(stage, line string) {
+	if c.hooks.Progress == nil {
+		return
+	}
+	if progress, ok := parseProgress(stage, line); ok {
+		c.hooks.Progress(progress)
+	}
+}